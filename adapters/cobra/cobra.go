@@ -0,0 +1,174 @@
+// Package cobra converts a spf13/cobra command tree into a cli2web schema
+package cobra
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/xhd2015/cli2web/config"
+)
+
+// schemaAnnotation marks the command added by NewSchemaCommand,
+// it is left out of the exported schema
+const schemaAnnotation = "cli2web-schema"
+
+// FromCommand walks cmd and its subcommands and returns the schema.
+// Persistent flags are exported as persistent options of the command
+// declaring them, the help command and help flags are skipped.
+func FromCommand(cmd *cobra.Command) *config.Schema {
+	c := &config.Command{
+		Name:        cmd.Name(),
+		Aliases:     cmd.Aliases,
+		Description: description(cmd),
+		Arguments:   parseUse(cmd.Use),
+	}
+	c.Hidden = cmd.Hidden
+	c.Deprecated = cmd.Deprecated
+	if example := strings.TrimSpace(cmd.Example); example != "" {
+		c.Examples = []*config.Example{{Usage: example}}
+	}
+	if len(cmd.ValidArgs) > 0 {
+		if len(c.Arguments) == 0 {
+			c.Arguments = []*config.Argument{{Name: "arg", Type: config.TypeString}}
+		}
+		c.Arguments[0].Choices = validArgs(cmd.ValidArgs)
+	}
+
+	cmd.LocalNonPersistentFlags().VisitAll(func(flag *pflag.Flag) {
+		if opt := convertFlag(flag); opt != nil {
+			c.Options = append(c.Options, opt)
+		}
+	})
+	cmd.PersistentFlags().VisitAll(func(flag *pflag.Flag) {
+		if opt := convertFlag(flag); opt != nil {
+			opt.Persistent = true
+			c.Options = append(c.Options, opt)
+		}
+	})
+
+	for _, sub := range cmd.Commands() {
+		if sub.Name() == "help" || sub.Annotations[schemaAnnotation] != "" {
+			continue
+		}
+		c.Commands = append(c.Commands, FromCommand(sub))
+	}
+	return c
+}
+
+// NewSchemaCommand returns a hidden `schema` command printing the
+// schema of the root command as JSON, add it to the root so that
+// `kool schema > schema.json` feeds cli2web
+func NewSchemaCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:         "schema",
+		Short:       "Print the cli2web schema of this command",
+		Hidden:      true,
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{schemaAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := json.MarshalIndent(FromCommand(cmd.Root()), "", "    ")
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), string(data))
+			return err
+		},
+	}
+}
+
+func description(cmd *cobra.Command) string {
+	if cmd.Long != "" {
+		return strings.TrimSpace(cmd.Long)
+	}
+	return cmd.Short
+}
+
+// parseUse extracts arguments from a use line like
+// "tag-next [flags] <dir> [files...]"
+func parseUse(use string) []*config.Argument {
+	fields := strings.Fields(use)
+	if len(fields) <= 1 {
+		return nil
+	}
+	var args []*config.Argument
+	for _, field := range fields[1:] {
+		name := strings.Trim(field, "<>[]")
+		name = strings.TrimSuffix(name, "...")
+		name = strings.Trim(name, "<>[]")
+		if name == "" || name == "flags" {
+			continue
+		}
+		args = append(args, &config.Argument{Name: name, Type: config.TypeString})
+	}
+	return args
+}
+
+// validArgs strips cobra's "value\tdescription" completion descriptions
+func validArgs(args []string) []string {
+	choices := make([]string, 0, len(args))
+	for _, arg := range args {
+		if idx := strings.Index(arg, "\t"); idx >= 0 {
+			arg = arg[:idx]
+		}
+		choices = append(choices, arg)
+	}
+	return choices
+}
+
+func convertFlag(flag *pflag.Flag) *config.Option {
+	if flag.Name == "help" {
+		return nil
+	}
+	placeholder, usage := pflag.UnquoteUsage(flag)
+	typ := flagType(flag.Value.Type())
+	if typ == config.TypeBoolean {
+		placeholder = ""
+	}
+	flags := "--" + flag.Name
+	if flag.Shorthand != "" {
+		flags = "-" + flag.Shorthand + ", " + flags
+	}
+	if placeholder != "" {
+		flags += " <" + placeholder + ">"
+	}
+
+	opt := &config.Option{
+		Flags:       flags,
+		Description: usage,
+		Type:        typ,
+		Default:     flagDefault(flag),
+	}
+	opt.Hidden = flag.Hidden
+	opt.Deprecated = flag.Deprecated
+	return opt
+}
+
+func flagType(valueType string) string {
+	switch valueType {
+	case "bool", "count":
+		// a count flag is sent once, like -v
+		return config.TypeBoolean
+	case "int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64",
+		"float32", "float64":
+		return config.TypeNumber
+	}
+	return config.TypeString
+}
+
+func flagDefault(flag *pflag.Flag) string {
+	switch flag.DefValue {
+	case "", "false", "[]":
+		return ""
+	}
+	switch flag.Value.Type() {
+	case "count":
+		return ""
+	case "stringSlice", "stringArray", "intSlice", "int64Slice", "uintSlice", "float64Slice", "float32Slice", "boolSlice", "durationSlice":
+		return strings.Trim(flag.DefValue, "[]")
+	}
+	return flag.DefValue
+}
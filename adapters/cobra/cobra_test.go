@@ -0,0 +1,111 @@
+package cobra
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/xhd2015/cli2web/config"
+)
+
+func TestFromCommand(t *testing.T) {
+	root := &cobra.Command{Use: "kool", Short: "Kool utilities"}
+	root.PersistentFlags().StringP("config", "c", "$HOME/.kool.yaml", "config `file`")
+	root.PersistentFlags().CountP("verbose", "v", "verbosity")
+
+	git := &cobra.Command{Use: "git", Short: "Git commands", Aliases: []string{"g"}}
+	tagNext := &cobra.Command{
+		Use:       "tag-next [flags] <dir>",
+		Short:     "Get the next git tag",
+		Example:   "  kool git tag-next --push .",
+		ValidArgs: []string{".\tcurrent directory", "sub"},
+		Run:       func(cmd *cobra.Command, args []string) {},
+	}
+	tagNext.Flags().BoolP("push", "p", false, "push the tag")
+	tagNext.Flags().Int("retries", 3, "retry times")
+	tagNext.Flags().StringSlice("label", []string{"a", "b"}, "labels")
+	tagNext.Flags().String("old", "", "old flag")
+	tagNext.Flags().MarkDeprecated("old", "use --label")
+	legacy := &cobra.Command{Use: "legacy", Hidden: true, Deprecated: "use tag-next", Run: func(cmd *cobra.Command, args []string) {}}
+	git.AddCommand(tagNext, legacy)
+	root.AddCommand(git, NewSchemaCommand())
+
+	s := FromCommand(root)
+	if s.Name != "kool" || s.Description != "Kool utilities" {
+		t.Errorf("Unexpected root %s: %s", s.Name, s.Description)
+	}
+	if len(s.Options) != 2 || !s.Options[0].Persistent {
+		t.Fatalf("Expected 2 persistent options, got %+v", s.Options)
+	}
+	if s.Options[0].Flags != "-c, --config <file>" || s.Options[0].Default != "$HOME/.kool.yaml" || s.Options[0].Description != "config file" {
+		t.Errorf("Unexpected config option %+v", s.Options[0])
+	}
+	if s.Options[1].Flags != "-v, --verbose" || s.Options[1].Type != config.TypeBoolean {
+		t.Errorf("Unexpected verbose option %+v", s.Options[1])
+	}
+	// the schema command is left out
+	if len(s.Commands) != 1 || s.Commands[0].Name != "git" || s.Commands[0].Aliases[0] != "g" {
+		t.Fatalf("Expected only git, got %+v", s.Commands)
+	}
+
+	cmds := s.Commands[0].Commands
+	if len(cmds) != 2 {
+		t.Fatalf("Expected 2 git subcommands, got %d", len(cmds))
+	}
+	if !cmds[0].Hidden || cmds[0].Deprecated != "use tag-next" {
+		t.Errorf("Expected legacy hidden and deprecated, got %+v", cmds[0].Lifecycle)
+	}
+	tn := cmds[1]
+	if len(tn.Arguments) != 1 || tn.Arguments[0].Name != "dir" {
+		t.Fatalf("Unexpected arguments %+v", tn.Arguments)
+	}
+	if got := tn.Arguments[0].Choices; len(got) != 2 || got[0] != "." || got[1] != "sub" {
+		t.Errorf("Unexpected choices %v", got)
+	}
+	if len(tn.Examples) != 1 || tn.Examples[0].Usage != "kool git tag-next --push ." {
+		t.Errorf("Unexpected examples %+v", tn.Examples)
+	}
+	// sorted by name, the same as cobra's help
+	expected := []struct {
+		flags, typ, def, deprecated string
+	}{
+		{"--label <strings>", config.TypeString, "a,b", ""},
+		{"--old <string>", config.TypeString, "", "use --label"},
+		{"-p, --push", config.TypeBoolean, "", ""},
+		{"--retries <int>", config.TypeNumber, "3", ""},
+	}
+	if len(tn.Options) != len(expected) {
+		t.Fatalf("Expected %d options, got %d", len(expected), len(tn.Options))
+	}
+	for i, e := range expected {
+		opt := tn.Options[i]
+		if opt.Flags != e.flags || opt.Type != e.typ || opt.Default != e.def || opt.Deprecated != e.deprecated || opt.Persistent {
+			t.Errorf("Option %d = %+v, expected %+v", i, opt, e)
+		}
+	}
+}
+
+func TestSchemaCommand(t *testing.T) {
+	root := &cobra.Command{Use: "kool"}
+	root.AddCommand(&cobra.Command{Use: "git", Run: func(cmd *cobra.Command, args []string) {}}, NewSchemaCommand())
+
+	var out bytes.Buffer
+	root.SetOut(&out)
+	root.SetArgs([]string{"schema"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	var s config.Schema
+	if err := json.Unmarshal(out.Bytes(), &s); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if s.Name != "kool" {
+		t.Errorf("Name = %q, expected kool", s.Name)
+	}
+	for _, cmd := range s.Commands {
+		if cmd.Name == "schema" {
+			t.Errorf("Expected schema command to be left out")
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Package urfave converts a urfave/cli/v2 app into a cli2web schema
+package urfave
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"github.com/xhd2015/cli2web/config"
+)
+
+// FromApp walks app and its commands and returns the schema.
+// urfave/cli only accepts a command's flags before its subcommands,
+// so the flags of the app and of commands with subcommands are
+// exported as persistent options placed before the subcommand.
+func FromApp(app *cli.App) *config.Schema {
+	return &config.Command{
+		Name:                app.Name,
+		Description:         description(app.Usage, app.Description),
+		Arguments:           parseArgsUsage(app.ArgsUsage),
+		Options:             convertFlags(app.Flags, len(app.Commands) > 0),
+		Commands:            convertCommands(app.Commands),
+		PersistentPlacement: config.PlacementBefore,
+	}
+}
+
+// SchemaCommand returns a hidden `schema` command printing the schema
+// of the app as JSON, add it to app.Commands so that
+// `kool schema > schema.json` feeds cli2web
+func SchemaCommand() *cli.Command {
+	return &cli.Command{
+		Name:   schemaCommandName,
+		Usage:  "Print the cli2web schema of this command",
+		Hidden: true,
+		Action: func(ctx *cli.Context) error {
+			data, err := json.MarshalIndent(FromApp(ctx.App), "", "    ")
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintln(ctx.App.Writer, string(data))
+			return err
+		},
+	}
+}
+
+const schemaCommandName = "schema"
+
+func convertCommands(commands []*cli.Command) []*config.Command {
+	var result []*config.Command
+	for _, cmd := range commands {
+		if cmd.Name == "help" || cmd.Name == schemaCommandName && cmd.Hidden {
+			continue
+		}
+		c := &config.Command{
+			Name:        cmd.Name,
+			Aliases:     cmd.Aliases,
+			Description: description(cmd.Usage, cmd.Description),
+			Arguments:   parseArgsUsage(cmd.ArgsUsage),
+			Options:     convertFlags(cmd.Flags, len(cmd.Subcommands) > 0),
+			Commands:    convertCommands(cmd.Subcommands),
+		}
+		c.Hidden = cmd.Hidden
+		result = append(result, c)
+	}
+	return result
+}
+
+func description(usage string, description string) string {
+	if description != "" {
+		return strings.TrimSpace(description)
+	}
+	return usage
+}
+
+// parseArgsUsage extracts arguments from an args usage like "<dir> [files...]"
+func parseArgsUsage(argsUsage string) []*config.Argument {
+	var args []*config.Argument
+	for _, field := range strings.Fields(argsUsage) {
+		name := strings.Trim(field, "<>[]")
+		name = strings.TrimSuffix(name, "...")
+		name = strings.Trim(name, "<>[]")
+		if name == "" {
+			continue
+		}
+		args = append(args, &config.Argument{Name: name, Type: config.TypeString})
+	}
+	return args
+}
+
+func convertFlags(flags []cli.Flag, persistent bool) []*config.Option {
+	var options []*config.Option
+	for _, flag := range flags {
+		if flag == cli.HelpFlag || flag == cli.VersionFlag {
+			continue
+		}
+		opt := convertFlag(flag)
+		opt.Persistent = persistent
+		options = append(options, opt)
+	}
+	return options
+}
+
+func convertFlag(flag cli.Flag) *config.Option {
+	opt := &config.Option{
+		Type: flagType(flag),
+	}
+	var placeholder string
+	if docFlag, ok := flag.(cli.DocGenerationFlag); ok {
+		placeholder, opt.Description = unquoteUsage(docFlag.GetUsage())
+		if docFlag.TakesValue() {
+			if placeholder == "" {
+				// the same as urfave/cli's help
+				placeholder = "value"
+			}
+			opt.Default = flagDefault(docFlag)
+		} else {
+			placeholder = ""
+		}
+	}
+	if opt.Type == config.TypeBoolean {
+		placeholder = ""
+	}
+	if visibleFlag, ok := flag.(cli.VisibleFlag); ok {
+		opt.Hidden = !visibleFlag.IsVisible()
+	}
+
+	var shorts, longs []string
+	for _, name := range flag.Names() {
+		if len(name) == 1 {
+			shorts = append(shorts, "-"+name)
+		} else {
+			longs = append(longs, "--"+name)
+		}
+	}
+	opt.Flags = strings.Join(append(shorts, longs...), ", ")
+	if placeholder != "" {
+		opt.Flags += " <" + placeholder + ">"
+	}
+	return opt
+}
+
+func flagType(flag cli.Flag) string {
+	switch flag.(type) {
+	case *cli.BoolFlag:
+		return config.TypeBoolean
+	case *cli.IntFlag, *cli.Int64Flag, *cli.UintFlag, *cli.Uint64Flag, *cli.Float64Flag:
+		return config.TypeNumber
+	}
+	return config.TypeString
+}
+
+// flagDefault converts urfave/cli's default text, which quotes
+// strings and lists, to a plain form value
+func flagDefault(flag cli.DocGenerationFlag) string {
+	text := flag.GetDefaultText()
+	var values []string
+	for _, value := range strings.Split(text, ", ") {
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		values = append(values, value)
+	}
+	def := strings.Join(values, ",")
+	if def == "false" {
+		return ""
+	}
+	return def
+}
+
+// unquoteUsage extracts a back-quoted placeholder name from usage,
+// the same convention as urfave/cli and the flag package
+func unquoteUsage(usage string) (string, string) {
+	start := strings.Index(usage, "`")
+	if start < 0 {
+		return "", usage
+	}
+	end := strings.Index(usage[start+1:], "`")
+	if end < 0 {
+		return "", usage
+	}
+	name := usage[start+1 : start+1+end]
+	return name, usage[:start] + name + usage[start+1+end+1:]
+}
@@ -0,0 +1,99 @@
+package urfave
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+	"github.com/xhd2015/cli2web/config"
+)
+
+func TestFromApp(t *testing.T) {
+	app := &cli.App{
+		Name:  "kool",
+		Usage: "the kool tool",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "format", Aliases: []string{"f"}, Value: "text", Usage: "output `FORMAT`"},
+			&cli.BoolFlag{Name: "dry-run", Usage: "print without executing"},
+		},
+		Commands: []*cli.Command{
+			{
+				Name:    "git",
+				Aliases: []string{"g"},
+				Usage:   "Git commands",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "tag-next",
+						Usage:     "Get the next git tag",
+						ArgsUsage: "<dir>",
+						Flags: []cli.Flag{
+							&cli.IntFlag{Name: "retries", Value: 3, Usage: "retry times"},
+							&cli.StringSliceFlag{Name: "label", Value: cli.NewStringSlice("a", "b")},
+							&cli.StringFlag{Name: "token", Hidden: true},
+						},
+					},
+				},
+			},
+			SchemaCommand(),
+		},
+	}
+
+	s := FromApp(app)
+	if s.Name != "kool" || s.Description != "the kool tool" || s.PersistentPlacement != config.PlacementBefore {
+		t.Errorf("Unexpected root %+v", s)
+	}
+	if len(s.Options) != 2 {
+		t.Fatalf("Expected 2 options, got %d", len(s.Options))
+	}
+	if opt := s.Options[0]; opt.Flags != "-f, --format <FORMAT>" || opt.Default != "text" || opt.Description != "output FORMAT" || !opt.Persistent {
+		t.Errorf("Unexpected format option %+v", opt)
+	}
+	if opt := s.Options[1]; opt.Flags != "--dry-run" || opt.Type != config.TypeBoolean || opt.Default != "" {
+		t.Errorf("Unexpected dry-run option %+v", opt)
+	}
+	if len(s.Commands) != 1 || s.Commands[0].Aliases[0] != "g" || len(s.Commands[0].Commands) != 1 {
+		t.Fatalf("Expected only git with tag-next, got %+v", s.Commands)
+	}
+
+	tagNext := s.Commands[0].Commands[0]
+	if len(tagNext.Arguments) != 1 || tagNext.Arguments[0].Name != "dir" {
+		t.Errorf("Unexpected arguments %+v", tagNext.Arguments)
+	}
+	expected := []struct {
+		flags, typ, def string
+		hidden          bool
+	}{
+		{"--retries <value>", config.TypeNumber, "3", false},
+		{"--label <value>", config.TypeString, "a,b", false},
+		{"--token <value>", config.TypeString, "", true},
+	}
+	if len(tagNext.Options) != len(expected) {
+		t.Fatalf("Expected %d options, got %d", len(expected), len(tagNext.Options))
+	}
+	for i, e := range expected {
+		opt := tagNext.Options[i]
+		if opt.Flags != e.flags || opt.Type != e.typ || opt.Default != e.def || opt.Hidden != e.hidden || opt.Persistent {
+			t.Errorf("Option %d = %+v, expected %+v", i, opt, e)
+		}
+	}
+}
+
+func TestSchemaCommand(t *testing.T) {
+	var out bytes.Buffer
+	app := &cli.App{
+		Name:     "kool",
+		Writer:   &out,
+		Commands: []*cli.Command{{Name: "git"}, SchemaCommand()},
+	}
+	if err := app.Run([]string{"kool", "schema"}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	var s config.Schema
+	if err := json.Unmarshal(out.Bytes(), &s); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if s.Name != "kool" || len(s.Commands) != 1 || s.Commands[0].Name != "git" {
+		t.Errorf("Unexpected schema %+v", s)
+	}
+}
@@ -0,0 +1,139 @@
+package completion
+
+import (
+	"fmt"
+	"strings"
+)
+
+func generateBash(root *command) string {
+	fn := "_" + funcName(root.names[0]) + "_completion"
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# bash completion for %s, generated by cli2web\n", root.names[0]))
+	sb.WriteString(fn + "() {\n")
+	sb.WriteString(`    local cur="${COMP_WORDS[COMP_CWORD]}" prev=""
+    if [[ $COMP_CWORD -gt 0 ]]; then
+        prev="${COMP_WORDS[COMP_CWORD-1]}"
+    fi
+`)
+	sb.WriteString(fmt.Sprintf("    local cmdpath=%s nargs=0 skip=0 i word\n", singleQuote(root.path)))
+	sb.WriteString(`    for ((i = 1; i < COMP_CWORD; i++)); do
+        word="${COMP_WORDS[i]}"
+        if [[ $skip -eq 1 ]]; then
+            skip=0
+            continue
+        fi
+        case "$word" in
+        -*=*) continue ;;
+        -*)
+            case "$cmdpath|$word" in
+`)
+	writeBashCases(&sb, "            ", valueFlagPatterns(root), "skip=1 ;;")
+	sb.WriteString(`            esac
+            continue
+            ;;
+        esac
+        case "$cmdpath $word" in
+`)
+	root.walk(func(cmd *command) {
+		for _, sub := range cmd.subcommands {
+			var patterns []string
+			for _, name := range sub.names {
+				patterns = append(patterns, singleQuote(cmd.path+" "+name))
+			}
+			sb.WriteString(fmt.Sprintf("        %s) cmdpath=%s ;;\n", strings.Join(patterns, " | "), singleQuote(sub.path)))
+		}
+	})
+	sb.WriteString(`        *) nargs=$((nargs + 1)) ;;
+        esac
+    done
+
+    case "$cmdpath|$prev" in
+`)
+	root.walk(func(cmd *command) {
+		for _, f := range cmd.flags {
+			if f.value == nil {
+				continue
+			}
+			var patterns []string
+			for _, name := range f.names {
+				patterns = append(patterns, singleQuote(cmd.path+"|"+name))
+			}
+			sb.WriteString(fmt.Sprintf("    %s)\n        %s\n        return\n        ;;\n", strings.Join(patterns, " | "), bashValueReply(f.value)))
+		}
+	})
+	sb.WriteString(`    esac
+
+    if [[ "$cur" == -* ]]; then
+        case "$cmdpath" in
+`)
+	root.walk(func(cmd *command) {
+		if names := cmd.flagNames(); len(names) > 0 {
+			sb.WriteString(fmt.Sprintf("        %s) COMPREPLY=($(compgen -W %s -- \"$cur\")) ;;\n", singleQuote(cmd.path), singleQuote(strings.Join(names, " "))))
+		}
+	})
+	sb.WriteString(`        esac
+        return
+    fi
+
+    case "$cmdpath|$nargs" in
+`)
+	root.walk(func(cmd *command) {
+		for i, arg := range cmd.args {
+			if len(arg.choices) == 0 && !arg.path {
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("    %s)\n        %s\n        return\n        ;;\n", singleQuote(fmt.Sprintf("%s|%d", cmd.path, i)), bashValueReply(arg)))
+		}
+	})
+	sb.WriteString(`    esac
+
+    case "$cmdpath" in
+`)
+	root.walk(func(cmd *command) {
+		if names := cmd.subcommandNames(); len(names) > 0 {
+			sb.WriteString(fmt.Sprintf("    %s) COMPREPLY=($(compgen -W %s -- \"$cur\")) ;;\n", singleQuote(cmd.path), singleQuote(strings.Join(names, " "))))
+		}
+	})
+	sb.WriteString("    esac\n}\n\n")
+	sb.WriteString(fmt.Sprintf("complete -o default -F %s %s\n", fn, root.names[0]))
+	return sb.String()
+}
+
+// valueFlagPatterns lists "path|flag" of the flags taking a value,
+// their next word is not a subcommand or an argument
+func valueFlagPatterns(root *command) []string {
+	var patterns []string
+	root.walk(func(cmd *command) {
+		for _, f := range cmd.flags {
+			if f.value == nil {
+				continue
+			}
+			for _, name := range f.names {
+				patterns = append(patterns, cmd.path+"|"+name)
+			}
+		}
+	})
+	return patterns
+}
+
+func writeBashCases(sb *strings.Builder, indent string, patterns []string, action string) {
+	if len(patterns) == 0 {
+		return
+	}
+	quoted := make([]string, len(patterns))
+	for i, p := range patterns {
+		quoted[i] = singleQuote(p)
+	}
+	sb.WriteString(indent + strings.Join(quoted, " | ") + ") " + action + "\n")
+}
+
+func bashValueReply(v *value) string {
+	if len(v.choices) > 0 {
+		return fmt.Sprintf(`COMPREPLY=($(compgen -W %s -- "$cur"))`, singleQuote(strings.Join(v.choices, " ")))
+	}
+	if v.path {
+		return `COMPREPLY=($(compgen -f -- "$cur"))`
+	}
+	// free text, nothing to suggest
+	return "COMPREPLY=()"
+}
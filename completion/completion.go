@@ -0,0 +1,156 @@
+// Package completion generates shell completion scripts for
+// the root command of a schema
+package completion
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xhd2015/cli2web/config"
+)
+
+// Shells supported by Generate
+const (
+	Bash       = "bash"
+	Zsh        = "zsh"
+	Fish       = "fish"
+	PowerShell = "powershell"
+)
+
+// Generate returns the completion script of s for shell. It completes
+// subcommands, flags including inherited persistent ones, choices of
+// options and arguments, and file paths for path typed ones. Hidden
+// commands and options are left out.
+func Generate(s *config.Schema, shell string) (string, error) {
+	if s.Name == "" {
+		return "", fmt.Errorf("schema requires a root name to complete")
+	}
+	root := buildCommand([]*config.Command{s})
+	switch shell {
+	case Bash:
+		return generateBash(root), nil
+	case Zsh:
+		return generateZsh(root), nil
+	case Fish:
+		return generateFish(root), nil
+	case PowerShell:
+		return generatePowerShell(root), nil
+	}
+	return "", fmt.Errorf("unrecognized shell: %s, expect bash, zsh, fish or powershell", shell)
+}
+
+// command is a command prepared for completion
+type command struct {
+	// path is the canonical command path, e.g. "kool git tag-next"
+	path        string
+	names       []string
+	description string
+	subcommands []*command
+	flags       []*flag
+	args        []*value
+}
+
+type flag struct {
+	names       []string
+	description string
+	// value is nil for flags without value
+	value *value
+}
+
+// value describes what an option or argument accepts
+type value struct {
+	choices []string
+	path    bool
+}
+
+func buildCommand(chain []*config.Command) *command {
+	cmd := chain[len(chain)-1]
+	names := make([]string, 0, len(chain))
+	for _, c := range chain {
+		names = append(names, c.Name)
+	}
+	c := &command{
+		path:        strings.Join(names, " "),
+		names:       append([]string{cmd.Name}, cmd.Aliases...),
+		description: firstLine(cmd.Description),
+	}
+	options := cmd.Options
+	for _, inherited := range config.InheritedOptions(chain) {
+		options = append(options[:len(options):len(options)], inherited.Option)
+	}
+	for _, opt := range options {
+		if opt.Hidden {
+			continue
+		}
+		spec := opt.Spec()
+		if spec.Name == "" {
+			continue
+		}
+		f := &flag{
+			names:       spec.Names(),
+			description: firstLine(opt.Description),
+		}
+		// the same rule as the web UI's invocation
+		if opt.Type != config.TypeBoolean && (opt.Type != "" || spec.Placeholder != "") {
+			f.value = &value{choices: opt.Choices, path: opt.Type == config.TypePath}
+		}
+		c.flags = append(c.flags, f)
+	}
+	for _, arg := range cmd.Arguments {
+		c.args = append(c.args, &value{choices: arg.Choices, path: arg.Type == config.TypePath})
+	}
+	for _, sub := range config.SortedCommands(cmd.Commands) {
+		if sub.Hidden || sub.Name == "" {
+			continue
+		}
+		c.subcommands = append(c.subcommands, buildCommand(append(chain[:len(chain):len(chain)], sub)))
+	}
+	return c
+}
+
+// walk calls fn for cmd and all its descendants
+func (c *command) walk(fn func(cmd *command)) {
+	fn(c)
+	for _, sub := range c.subcommands {
+		sub.walk(fn)
+	}
+}
+
+// subcommandNames lists names and aliases of the subcommands
+func (c *command) subcommandNames() []string {
+	var names []string
+	for _, sub := range c.subcommands {
+		names = append(names, sub.names...)
+	}
+	return names
+}
+
+func (c *command) flagNames() []string {
+	var names []string
+	for _, f := range c.flags {
+		names = append(names, f.names...)
+	}
+	return names
+}
+
+func firstLine(s string) string {
+	if idx := strings.Index(s, "\n"); idx >= 0 {
+		return strings.TrimSpace(s[:idx])
+	}
+	return s
+}
+
+// funcName converts the root name to a shell function name
+func funcName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// singleQuote quotes s for sh, bash, zsh and fish
+func singleQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
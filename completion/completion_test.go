@@ -0,0 +1,160 @@
+package completion
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/cli2web/config"
+)
+
+func testSchema() *config.Schema {
+	return &config.Schema{
+		Name: "kool",
+		Options: []*config.Option{
+			{Flags: "-v,--verbose", Type: config.TypeBoolean, Persistent: true, Description: "verbose output"},
+		},
+		Commands: []*config.Command{
+			{
+				Name:        "git",
+				Aliases:     []string{"g"},
+				Description: "Git helpers",
+				Commands: []*config.Command{
+					{
+						Name:        "tag-next",
+						Description: "Tag the next version",
+						Options: []*config.Option{
+							{Flags: "--format <fmt>", Choices: []string{"json", "text"}},
+							{Flags: "-o,--out <file>", Type: config.TypePath},
+							{Flags: "--secret-mode", Type: config.TypeBoolean, Lifecycle: config.Lifecycle{Hidden: true}},
+						},
+						Arguments: []*config.Argument{
+							{Name: "kind", Choices: []string{"major", "minor"}},
+							{Name: "file", Type: config.TypePath},
+						},
+					},
+				},
+			},
+			{Name: "internal", Lifecycle: config.Lifecycle{Hidden: true}},
+		},
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		shell    string
+		contains []string
+	}{
+		{
+			shell: Bash,
+			contains: []string{
+				`'kool git' | 'kool g') cmdpath='kool git' ;;`,
+				`COMPREPLY=($(compgen -W 'json text' -- "$cur"))`,
+				`'kool git tag-next') COMPREPLY=($(compgen -W '--format -o --out -v --verbose' -- "$cur")) ;;`,
+				`complete -o default -F _kool_completion kool`,
+			},
+		},
+		{
+			shell: Zsh,
+			contains: []string{
+				"#compdef kool",
+				`candidates=('git:Git helpers' 'g:Git helpers')`,
+				`compadd -- 'major' 'minor'`,
+				"_files",
+				"compdef _kool kool",
+			},
+		},
+		{
+			shell: Fish,
+			contains: []string{
+				`complete -c kool -n 'test (__kool_cmdpath) = "kool"' -a 'git' -d 'Git helpers'`,
+				`complete -c kool -n 'test (__kool_cmdpath) = "kool git tag-next"' -l 'format' -x -a 'json text'`,
+				`complete -c kool -n 'test (__kool_cmdpath) = "kool git tag-next"' -s 'o' -l 'out' -r -F`,
+			},
+		},
+		{
+			shell: PowerShell,
+			contains: []string{
+				"Register-ArgumentCompleter -Native -CommandName 'kool'",
+				`'kool g' = 'kool git'`,
+				`'kool git tag-next|--format' = @('json', 'text')`,
+				`'kool git tag-next|0' = @('major', 'minor')`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			script, err := Generate(testSchema(), tt.shell)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(script, s) {
+					t.Errorf("expect script to contain %q, got:\n%s", s, script)
+				}
+			}
+			for _, s := range []string{"internal", "secret-mode"} {
+				if strings.Contains(script, s) {
+					t.Errorf("expect hidden %q to be left out", s)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerate_Errors(t *testing.T) {
+	if _, err := Generate(&config.Schema{}, Bash); err == nil || err.Error() != "schema requires a root name to complete" {
+		t.Errorf("expect error for unnamed schema, got %v", err)
+	}
+	if _, err := Generate(testSchema(), "tcsh"); err == nil || err.Error() != "unrecognized shell: tcsh, expect bash, zsh, fish or powershell" {
+		t.Errorf("expect error for unknown shell, got %v", err)
+	}
+}
+
+func TestBashCompletion(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not found")
+	}
+	script, err := Generate(testSchema(), Bash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		line   string
+		expect string
+	}{
+		{"kool ", "git g"},
+		{"kool g", "git g"},
+		{"kool g ", "tag-next"},
+		{"kool git tag-next --f", "--format"},
+		{"kool git tag-next --format ", "json text"},
+		{"kool -v git tag-next --format json ", "major minor"},
+		{"kool git tag-next --out x m", "major minor"},
+		{"kool git tag-next -", "--format -o --out -v --verbose"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			// simulate bash splitting the line into COMP_WORDS
+			words := strings.Fields(tt.line)
+			if strings.HasSuffix(tt.line, " ") {
+				words = append(words, "")
+			}
+			quoted := make([]string, len(words))
+			for i, w := range words {
+				quoted[i] = singleQuote(w)
+			}
+			test := script + "\nCOMP_WORDS=(" + strings.Join(quoted, " ") + ")\n" +
+				"COMP_CWORD=" + strconv.Itoa(len(words)-1) + "\n" +
+				"_kool_completion\necho \"${COMPREPLY[*]}\"\n"
+			out, err := exec.Command(bash, "-c", test).CombinedOutput()
+			if err != nil {
+				t.Fatalf("bash: %v\n%s", err, out)
+			}
+			if got := strings.TrimSpace(string(out)); got != tt.expect {
+				t.Errorf("expect %q, got %q", tt.expect, got)
+			}
+		})
+	}
+}
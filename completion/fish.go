@@ -0,0 +1,100 @@
+package completion
+
+import (
+	"fmt"
+	"strings"
+)
+
+func generateFish(root *command) string {
+	name := root.names[0]
+	fn := "__" + funcName(name) + "_cmdpath"
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# fish completion for %s, generated by cli2web\n\n", name))
+
+	// the function prints the canonical path of the command being completed
+	sb.WriteString(fmt.Sprintf("function %s\n", fn))
+	sb.WriteString("    set -l words (commandline -opc)\n")
+	sb.WriteString(fmt.Sprintf("    set -l cmdpath %s\n", singleQuote(root.path)))
+	sb.WriteString("    set -l skip 0\n")
+	sb.WriteString("    for word in $words[2..-1]\n")
+	sb.WriteString("        if test $skip -eq 1\n            set skip 0\n            continue\n        end\n")
+	sb.WriteString("        switch \"$cmdpath|$word\"\n")
+	if patterns := valueFlagPatterns(root); len(patterns) > 0 {
+		sb.WriteString("            case " + quoteAll(patterns) + "\n                set skip 1\n                continue\n")
+	}
+	sb.WriteString("            case '*|-*'\n                continue\n        end\n")
+	sb.WriteString("        switch \"$cmdpath $word\"\n")
+	root.walk(func(cmd *command) {
+		for _, sub := range cmd.subcommands {
+			var patterns []string
+			for _, name := range sub.names {
+				patterns = append(patterns, cmd.path+" "+name)
+			}
+			sb.WriteString(fmt.Sprintf("            case %s\n                set cmdpath %s\n", quoteAll(patterns), singleQuote(sub.path)))
+		}
+	})
+	sb.WriteString("        end\n    end\n    echo $cmdpath\nend\n\n")
+
+	sb.WriteString(fmt.Sprintf("complete -c %s -f\n", name))
+	root.walk(func(cmd *command) {
+		cond := fmt.Sprintf("-n %s", singleQuote(fmt.Sprintf("test (%s) = %s", fn, fishQuote(cmd.path))))
+		for _, sub := range cmd.subcommands {
+			for _, subName := range sub.names {
+				line := fmt.Sprintf("complete -c %s %s -a %s", name, cond, singleQuote(subName))
+				if sub.description != "" {
+					line += " -d " + singleQuote(sub.description)
+				}
+				sb.WriteString(line + "\n")
+			}
+		}
+		for _, f := range cmd.flags {
+			line := fmt.Sprintf("complete -c %s %s", name, cond)
+			for _, flagName := range f.names {
+				switch {
+				case strings.HasPrefix(flagName, "--"):
+					line += " -l " + singleQuote(flagName[2:])
+				case len(flagName) == 2:
+					line += " -s " + singleQuote(flagName[1:])
+				default:
+					line += " -o " + singleQuote(flagName[1:])
+				}
+			}
+			if f.value != nil {
+				switch {
+				case len(f.value.choices) > 0:
+					line += " -x -a " + singleQuote(strings.Join(f.value.choices, " "))
+				case f.value.path:
+					line += " -r -F"
+				default:
+					line += " -r"
+				}
+			}
+			if f.description != "" {
+				line += " -d " + singleQuote(f.description)
+			}
+			sb.WriteString(line + "\n")
+		}
+		for _, arg := range cmd.args {
+			switch {
+			case len(arg.choices) > 0:
+				sb.WriteString(fmt.Sprintf("complete -c %s %s -a %s\n", name, cond, singleQuote(strings.Join(arg.choices, " "))))
+			case arg.path:
+				sb.WriteString(fmt.Sprintf("complete -c %s %s -F\n", name, cond))
+			}
+		}
+	})
+	return sb.String()
+}
+
+// fishQuote double quotes s inside a single quoted condition
+func fishQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`).Replace(s) + `"`
+}
+
+func quoteAll(list []string) string {
+	quoted := make([]string, len(list))
+	for i, s := range list {
+		quoted[i] = singleQuote(s)
+	}
+	return strings.Join(quoted, " ")
+}
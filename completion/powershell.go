@@ -0,0 +1,125 @@
+package completion
+
+import (
+	"fmt"
+	"strings"
+)
+
+func generatePowerShell(root *command) string {
+	name := root.names[0]
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# powershell completion for %s, generated by cli2web\n\n", name))
+	sb.WriteString(fmt.Sprintf("Register-ArgumentCompleter -Native -CommandName %s -ScriptBlock {\n", psQuote(name)))
+	sb.WriteString("    param($wordToComplete, $commandAst, $cursorPosition)\n\n")
+
+	// "path word" -> child path
+	sb.WriteString("    $commandPaths = @{\n")
+	root.walk(func(cmd *command) {
+		for _, sub := range cmd.subcommands {
+			for _, subName := range sub.names {
+				sb.WriteString(fmt.Sprintf("        %s = %s\n", psQuote(cmd.path+" "+subName), psQuote(sub.path)))
+			}
+		}
+	})
+	sb.WriteString("    }\n")
+
+	sb.WriteString("    $subcommands = @{\n")
+	root.walk(func(cmd *command) {
+		if names := cmd.subcommandNames(); len(names) > 0 {
+			sb.WriteString(fmt.Sprintf("        %s = @(%s)\n", psQuote(cmd.path), psQuoteAll(names)))
+		}
+	})
+	sb.WriteString("    }\n")
+
+	sb.WriteString("    $flags = @{\n")
+	root.walk(func(cmd *command) {
+		if names := cmd.flagNames(); len(names) > 0 {
+			sb.WriteString(fmt.Sprintf("        %s = @(%s)\n", psQuote(cmd.path), psQuoteAll(names)))
+		}
+	})
+	sb.WriteString("    }\n")
+
+	// "path|flag" -> choices, empty for free text and paths
+	sb.WriteString("    $valueFlags = @{\n")
+	root.walk(func(cmd *command) {
+		for _, f := range cmd.flags {
+			if f.value == nil {
+				continue
+			}
+			for _, flagName := range f.names {
+				sb.WriteString(fmt.Sprintf("        %s = @(%s)\n", psQuote(cmd.path+"|"+flagName), psQuoteAll(f.value.choices)))
+			}
+		}
+	})
+	sb.WriteString("    }\n")
+
+	// "path|index" -> choices of the argument
+	sb.WriteString("    $argValues = @{\n")
+	root.walk(func(cmd *command) {
+		for i, arg := range cmd.args {
+			if len(arg.choices) == 0 {
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("        %s = @(%s)\n", psQuote(fmt.Sprintf("%s|%d", cmd.path, i)), psQuoteAll(arg.choices)))
+		}
+	})
+	sb.WriteString("    }\n\n")
+
+	sb.WriteString(fmt.Sprintf("    $cmdpath = %s\n", psQuote(root.path)))
+	sb.WriteString(`    $nargs = 0
+    $skip = $false
+    $prev = ''
+    $words = @($commandAst.CommandElements | Where-Object { $_.Extent.EndOffset -lt $cursorPosition } | ForEach-Object { $_.ToString() })
+    for ($i = 1; $i -lt $words.Count; $i++) {
+        $word = $words[$i]
+        if ($i -eq $words.Count - 1 -and $word -eq $wordToComplete) {
+            break
+        }
+        $prev = $word
+        if ($skip) {
+            $skip = $false
+            continue
+        }
+        if ($word.StartsWith('-')) {
+            if (-not $word.Contains('=') -and $valueFlags.ContainsKey("$cmdpath|$word")) {
+                $skip = $true
+            }
+            continue
+        }
+        if ($commandPaths.ContainsKey("$cmdpath $word")) {
+            $cmdpath = $commandPaths["$cmdpath $word"]
+        } else {
+            $nargs++
+        }
+    }
+
+    $candidates = @()
+    if ($skip) {
+        # complete the value of the previous flag, paths fall back to files
+        $candidates = $valueFlags["$cmdpath|$prev"]
+    } elseif ($wordToComplete.StartsWith('-')) {
+        $candidates = $flags[$cmdpath]
+    } elseif ($argValues.ContainsKey("$cmdpath|$nargs")) {
+        $candidates = $argValues["$cmdpath|$nargs"]
+    } else {
+        $candidates = $subcommands[$cmdpath]
+    }
+    $candidates | Where-Object { $_ -like "$wordToComplete*" } | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+`)
+	return sb.String()
+}
+
+func psQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func psQuoteAll(list []string) string {
+	quoted := make([]string, len(list))
+	for i, s := range list {
+		quoted[i] = psQuote(s)
+	}
+	return strings.Join(quoted, ", ")
+}
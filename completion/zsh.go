@@ -0,0 +1,136 @@
+package completion
+
+import (
+	"fmt"
+	"strings"
+)
+
+func generateZsh(root *command) string {
+	name := root.names[0]
+	fn := "_" + funcName(name)
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("#compdef %s\n# zsh completion for %s, generated by cli2web\n\n", name, name))
+	sb.WriteString(fn + "() {\n")
+	sb.WriteString(`    local cur="${words[CURRENT]}" prev="${words[CURRENT-1]}"
+`)
+	sb.WriteString(fmt.Sprintf("    local cmdpath=%s nargs=0 skip=0 i word\n", singleQuote(root.path)))
+	sb.WriteString(`    local -a candidates
+    for ((i = 2; i < CURRENT; i++)); do
+        word="${words[i]}"
+        if [[ $skip -eq 1 ]]; then
+            skip=0
+            continue
+        fi
+        case "$word" in
+        -*=*) continue ;;
+        -*)
+            case "$cmdpath|$word" in
+`)
+	writeBashCases(&sb, "            ", valueFlagPatterns(root), "skip=1 ;;")
+	sb.WriteString(`            esac
+            continue
+            ;;
+        esac
+        case "$cmdpath $word" in
+`)
+	root.walk(func(cmd *command) {
+		for _, sub := range cmd.subcommands {
+			var patterns []string
+			for _, name := range sub.names {
+				patterns = append(patterns, singleQuote(cmd.path+" "+name))
+			}
+			sb.WriteString(fmt.Sprintf("        %s) cmdpath=%s ;;\n", strings.Join(patterns, " | "), singleQuote(sub.path)))
+		}
+	})
+	sb.WriteString(`        *) nargs=$((nargs + 1)) ;;
+        esac
+    done
+
+    case "$cmdpath|$prev" in
+`)
+	root.walk(func(cmd *command) {
+		for _, f := range cmd.flags {
+			if f.value == nil {
+				continue
+			}
+			var patterns []string
+			for _, name := range f.names {
+				patterns = append(patterns, singleQuote(cmd.path+"|"+name))
+			}
+			sb.WriteString(fmt.Sprintf("    %s)\n        %s\n        return\n        ;;\n", strings.Join(patterns, " | "), zshValueReply(f.value)))
+		}
+	})
+	sb.WriteString(`    esac
+
+    if [[ "$cur" == -* ]]; then
+        case "$cmdpath" in
+`)
+	root.walk(func(cmd *command) {
+		if len(cmd.flags) == 0 {
+			return
+		}
+		var items []string
+		for _, f := range cmd.flags {
+			for _, name := range f.names {
+				items = append(items, zshItem(name, f.description))
+			}
+		}
+		sb.WriteString(fmt.Sprintf("        %s)\n            candidates=(%s)\n            _describe 'option' candidates\n            ;;\n", singleQuote(cmd.path), strings.Join(items, " ")))
+	})
+	sb.WriteString(`        esac
+        return
+    fi
+
+    case "$cmdpath|$nargs" in
+`)
+	root.walk(func(cmd *command) {
+		for i, arg := range cmd.args {
+			if len(arg.choices) == 0 && !arg.path {
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("    %s)\n        %s\n        return\n        ;;\n", singleQuote(fmt.Sprintf("%s|%d", cmd.path, i)), zshValueReply(arg)))
+		}
+	})
+	sb.WriteString(`    esac
+
+    case "$cmdpath" in
+`)
+	root.walk(func(cmd *command) {
+		if len(cmd.subcommands) == 0 {
+			return
+		}
+		var items []string
+		for _, sub := range cmd.subcommands {
+			for _, name := range sub.names {
+				items = append(items, zshItem(name, sub.description))
+			}
+		}
+		sb.WriteString(fmt.Sprintf("    %s)\n        candidates=(%s)\n        _describe 'command' candidates\n        ;;\n", singleQuote(cmd.path), strings.Join(items, " ")))
+	})
+	sb.WriteString("    *) _files ;;\n    esac\n}\n\n")
+	sb.WriteString(fmt.Sprintf("if [[ \"${funcstack[1]}\" == %s ]]; then\n    %s \"$@\"\nelse\n    compdef %s %s\nfi\n", fn, fn, fn, name))
+	return sb.String()
+}
+
+// zshItem formats a _describe candidate, colons in the name are escaped
+func zshItem(name string, description string) string {
+	item := strings.ReplaceAll(name, ":", `\:`)
+	if description != "" {
+		item += ":" + description
+	}
+	return singleQuote(item)
+}
+
+func zshValueReply(v *value) string {
+	if len(v.choices) > 0 {
+		quoted := make([]string, len(v.choices))
+		for i, c := range v.choices {
+			quoted[i] = singleQuote(c)
+		}
+		return "compadd -- " + strings.Join(quoted, " ")
+	}
+	if v.path {
+		return "_files"
+	}
+	return ":"
+}
@@ -1,40 +1,131 @@
 package config
 
+import "sort"
+
 // Schema represents the JSON schema
 type Schema = Command
 
 type Command struct {
-	Name        string      `json:"name"`
-	Description string      `json:"description"`
-	Commands    []*Command  `json:"commands"`
-	Examples    []*Example  `json:"examples"`
-	Options     []*Option   `json:"options"`
-	Arguments   []*Argument `json:"arguments"`
-	Output      *Output     `json:"output"`
+	Name        string      `json:"name" desc:"command name, a segment of the command line and the URL"`
+	Aliases     []string    `json:"aliases" desc:"alternative names of the command"`
+	Description string      `json:"description" desc:"what the command does"`
+	Commands    []*Command  `json:"commands" desc:"subcommands"`
+	Examples    []*Example  `json:"examples" desc:"example invocations"`
+	Options     []*Option   `json:"options" desc:"flags accepted by the command"`
+	Arguments   []*Argument `json:"arguments" desc:"positional arguments, in order"`
+	Output      *Output     `json:"output" desc:"what the command prints"`
+
+	// Groups lays out Options in named sections,
+	// options refer to them by Option.Group
+	Groups []*OptionGroup `json:"groups" desc:"named sections of the options form"`
+	// PersistentPlacement controls where persistent options declared
+	// by this command and its descendants go in argv: "after" (default)
+	// appends them after the leaf command, "before" puts them right
+	// after the declaring command's name
+	PersistentPlacement string `json:"persistentPlacement" desc:"where inherited persistent options go in argv" enum:"before,after"`
+	// Order sorts sibling commands ascending in the sidebar, docs and
+	// terminal UI, commands with the same order keep their declaration order
+	Order int `json:"order" desc:"sorts sibling commands ascending"`
+
+	Lifecycle
+}
+
+// SortedCommands returns commands sorted by Order,
+// commands is left as is
+func SortedCommands(commands []*Command) []*Command {
+	sorted := make([]*Command, len(commands))
+	copy(sorted, commands)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Order < sorted[j].Order
+	})
+	return sorted
+}
+
+const (
+	PlacementBefore = "before"
+	PlacementAfter  = "after"
+)
+
+// OptionGroup is a named section of a command's options form
+type OptionGroup struct {
+	Name        string `json:"name" desc:"group name, referenced by option.group"`
+	Description string `json:"description" desc:"shown under the group title"`
+	// Order sorts groups ascending, groups with the same
+	// order keep their declaration order
+	Order int `json:"order" desc:"sorts groups ascending"`
+	// Collapsed groups are folded until expanded
+	Collapsed bool `json:"collapsed" desc:"fold the group until expanded"`
+}
+
+// Lifecycle marks a command or option as hidden, deprecated or experimental
+type Lifecycle struct {
+	// Hidden items are left out of the sidebar and docs
+	Hidden bool `json:"hidden" desc:"leave out of the sidebar and docs"`
+	// Deprecated is the deprecation message, non-empty means deprecated
+	Deprecated string `json:"deprecated" desc:"deprecation message, non-empty means deprecated"`
+	// Replacement names what to use instead, a command path
+	// like "git tag-next" for commands, or a flag like "--format"
+	// of the same command for options
+	Replacement  string `json:"replacement" desc:"what to use instead, a command path or a flag"`
+	Experimental bool   `json:"experimental" desc:"mark as experimental"`
 }
 
+// Types of arguments and options
+const (
+	TypeBoolean = "boolean"
+	TypeString  = "string"
+	TypeNumber  = "number"
+	// TypePath is a file or directory path, a string
+	// completed from the file system in shells
+	TypePath = "path"
+)
+
 type Argument struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Type        string `json:"type"`
-	Default     string `json:"default"`
-	Multiline   bool   `json:"multiline"`
+	Name        string `json:"name" desc:"argument name"`
+	Description string `json:"description" desc:"what the argument is"`
+	Type        string `json:"type" desc:"value type" enum:"string,boolean,number,path"`
+	Default     string `json:"default" desc:"default value"`
+	Multiline   bool   `json:"multiline" desc:"render as a text area"`
+
+	// Choices, Secret, Env and Stdin behave the same as on Option
+	Choices []string `json:"choices" desc:"allowed values, rendered as a dropdown"`
+	Secret  bool     `json:"secret" desc:"render as a password input and mask in logs"`
+	Env     string   `json:"env" desc:"pass the value through this environment variable"`
+	Stdin   bool     `json:"stdin" desc:"write the value to stdin"`
 }
 
 type Example struct {
-	Usage       string `json:"usage"`
-	Description string `json:"description"`
+	Usage       string `json:"usage" desc:"the command line"`
+	Description string `json:"description" desc:"what the example does"`
 }
 
 type Option struct {
-	Flags       string `json:"flags"`
-	Description string `json:"description"`
-	Type        string `json:"type"`
-	Default     string `json:"default"`
-	Multiline   bool   `json:"multiline"`
+	Flags       string `json:"flags" desc:"flag names and placeholder, e.g. \"-f, --format <fmt>\""`
+	Description string `json:"description" desc:"what the option does"`
+	Type        string `json:"type" desc:"value type" enum:"string,boolean,number,path"`
+	Default     string `json:"default" desc:"default value"`
+	Multiline   bool   `json:"multiline" desc:"render as a text area"`
+	// Choices restricts the value to a list, rendered as a dropdown
+	Choices []string `json:"choices" desc:"allowed values, rendered as a dropdown"`
+
+	// Secret renders the value as a password input and masks it
+	// in logs and command line previews
+	Secret bool `json:"secret" desc:"render as a password input and mask in logs"`
+	// Env passes the value through the named environment
+	// variable instead of argv
+	Env string `json:"env" desc:"pass the value through this environment variable"`
+	// Stdin writes the value to the command's stdin instead of argv
+	Stdin bool `json:"stdin" desc:"write the value to stdin"`
+	// Group is the name of the OptionGroup this option belongs to,
+	// empty for the ungrouped options listed first
+	Group string `json:"group" desc:"name of the group the option belongs to"`
+	// Persistent options are inherited by all descendant commands
+	Persistent bool `json:"persistent" desc:"inherited by all descendant commands"`
+
+	Lifecycle
 }
 
 type Output struct {
-	Type        string `json:"type"`
-	Description string `json:"description"`
+	Type        string `json:"type" desc:"output format, e.g. text"`
+	Description string `json:"description" desc:"what the output is"`
 }
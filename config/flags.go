@@ -0,0 +1,121 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FlagSpec is the structured form of Option.Flags, which follows
+// commander.js conventions, e.g. "-f, --format <fmt>"
+type FlagSpec struct {
+	// Name is the canonical flag passed on the command line,
+	// the first long flag if any, e.g. --format
+	Name string
+	// Aliases are the other spellings in declaration order, e.g. -f
+	Aliases []string
+	// Placeholder is the value name, e.g. fmt, empty if the flag takes no value
+	Placeholder string
+	// OptionalValue is true when the value is declared as [fmt] instead of <fmt>
+	OptionalValue bool
+
+	// all flags in declaration order, used for display
+	names []string
+}
+
+// ParseFlags parses a flags string like "-p, --push" or "--format <fmt>"
+func ParseFlags(flags string) *FlagSpec {
+	spec := &FlagSpec{}
+	fields := strings.FieldsFunc(flags, func(r rune) bool {
+		return r == ',' || r == '|' || r == ' ' || r == '\t'
+	})
+	for _, field := range fields {
+		if !strings.HasPrefix(field, "-") {
+			spec.setPlaceholder(field)
+			continue
+		}
+		// --format=<fmt>
+		if idx := strings.Index(field, "="); idx > 0 {
+			spec.setPlaceholder(field[idx+1:])
+			field = field[:idx]
+		}
+		spec.names = append(spec.names, field)
+	}
+
+	canonical := -1
+	for i, name := range spec.names {
+		if strings.HasPrefix(name, "--") {
+			canonical = i
+			break
+		}
+	}
+	if canonical < 0 && len(spec.names) > 0 {
+		canonical = 0
+	}
+	for i, name := range spec.names {
+		if i == canonical {
+			spec.Name = name
+		} else {
+			spec.Aliases = append(spec.Aliases, name)
+		}
+	}
+	return spec
+}
+
+func (s *FlagSpec) setPlaceholder(field string) {
+	if field == "" || s.Placeholder != "" {
+		return
+	}
+	if strings.HasPrefix(field, "[") && strings.HasSuffix(field, "]") {
+		s.OptionalValue = true
+		field = field[1 : len(field)-1]
+	} else if strings.HasPrefix(field, "<") && strings.HasSuffix(field, ">") {
+		field = field[1 : len(field)-1]
+	}
+	s.Placeholder = field
+}
+
+// Names returns all spellings of the flag in declaration order
+func (s *FlagSpec) Names() []string {
+	return s.names
+}
+
+// Display renders the flag for humans, e.g. "-f, --format <fmt>"
+func (s *FlagSpec) Display() string {
+	display := strings.Join(s.names, ", ")
+	if s.Placeholder != "" {
+		if s.OptionalValue {
+			display += " [" + s.Placeholder + "]"
+		} else {
+			display += " <" + s.Placeholder + ">"
+		}
+	}
+	return display
+}
+
+// ID returns a stable identifier derived from the canonical name,
+// suitable as an HTML form field name, e.g. opt-format. Characters
+// other than letters, digits and '-' are escaped so that distinct
+// names get distinct IDs: '_' as "__", others by their UTF-8 bytes
+// like "_2e" for '.'.
+func (s *FlagSpec) ID() string {
+	name := strings.TrimLeft(s.Name, "-")
+	var sb strings.Builder
+	sb.WriteString("opt-")
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-':
+			sb.WriteByte(c)
+		case c == '_':
+			sb.WriteString("__")
+		default:
+			fmt.Fprintf(&sb, "_%02x", c)
+		}
+	}
+	return sb.String()
+}
+
+// Spec parses the option's Flags
+func (o *Option) Spec() *FlagSpec {
+	return ParseFlags(o.Flags)
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		flags         string
+		name          string
+		aliases       []string
+		placeholder   string
+		optionalValue bool
+		display       string
+		id            string
+	}{
+		{flags: "--push", name: "--push", display: "--push", id: "opt-push"},
+		{flags: "-p, --push", name: "--push", aliases: []string{"-p"}, display: "-p, --push", id: "opt-push"},
+		{flags: "--push, -p", name: "--push", aliases: []string{"-p"}, display: "--push, -p", id: "opt-push"},
+		{flags: "-v", name: "-v", display: "-v", id: "opt-v"},
+		{flags: "-f, --format <fmt>", name: "--format", aliases: []string{"-f"}, placeholder: "fmt", display: "-f, --format <fmt>", id: "opt-format"},
+		{flags: "--format=<fmt>", name: "--format", placeholder: "fmt", display: "--format <fmt>", id: "opt-format"},
+		{flags: "--level [n]", name: "--level", placeholder: "n", optionalValue: true, display: "--level [n]", id: "opt-level"},
+		{flags: "-o|--out FILE", name: "--out", aliases: []string{"-o"}, placeholder: "FILE", display: "-o, --out <FILE>", id: "opt-out"},
+		{flags: "--dry.run", name: "--dry.run", display: "--dry.run", id: "opt-dry_2erun"},
+		{flags: "--dry-run", name: "--dry-run", display: "--dry-run", id: "opt-dry-run"},
+		{flags: "--a_b", name: "--a_b", display: "--a_b", id: "opt-a__b"},
+		{flags: "--a_2eb", name: "--a_2eb", display: "--a_2eb", id: "opt-a__2eb"},
+		{flags: "", display: "", id: "opt-"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.flags, func(t *testing.T) {
+			spec := ParseFlags(tt.flags)
+			if spec.Name != tt.name {
+				t.Errorf("Name = %q, expected %q", spec.Name, tt.name)
+			}
+			if !reflect.DeepEqual(spec.Aliases, tt.aliases) {
+				t.Errorf("Aliases = %v, expected %v", spec.Aliases, tt.aliases)
+			}
+			if spec.Placeholder != tt.placeholder {
+				t.Errorf("Placeholder = %q, expected %q", spec.Placeholder, tt.placeholder)
+			}
+			if spec.OptionalValue != tt.optionalValue {
+				t.Errorf("OptionalValue = %v, expected %v", spec.OptionalValue, tt.optionalValue)
+			}
+			if spec.Display() != tt.display {
+				t.Errorf("Display() = %q, expected %q", spec.Display(), tt.display)
+			}
+			if spec.ID() != tt.id {
+				t.Errorf("ID() = %q, expected %q", spec.ID(), tt.id)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package config
+
+// InheritedOption is a persistent option declared by an ancestor command
+type InheritedOption struct {
+	*Option
+	// Depth is the index of the declaring command in the chain
+	Depth int
+	// Placement is the effective PersistentPlacement, PlacementBefore or PlacementAfter
+	Placement string
+}
+
+// InheritedOptions returns the persistent options the last command of chain
+// inherits from its ancestors, chain starts with the root. Options of nearer
+// commands shadow those of farther ones with the same canonical flag.
+// The result is ordered from the root down.
+func InheritedOptions(chain []*Command) []*InheritedOption {
+	if len(chain) == 0 {
+		return nil
+	}
+	leaf := chain[len(chain)-1]
+	seen := make(map[string]bool)
+	for _, opt := range leaf.Options {
+		seen[opt.Spec().Name] = true
+	}
+
+	var reversed []*InheritedOption
+	for depth := len(chain) - 2; depth >= 0; depth-- {
+		var declared []*InheritedOption
+		for _, opt := range chain[depth].Options {
+			if !opt.Persistent {
+				continue
+			}
+			name := opt.Spec().Name
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			declared = append(declared, &InheritedOption{
+				Option:    opt,
+				Depth:     depth,
+				Placement: placementAt(chain, depth),
+			})
+		}
+		// keep declaration order within a command
+		for i := len(declared) - 1; i >= 0; i-- {
+			reversed = append(reversed, declared[i])
+		}
+	}
+
+	result := make([]*InheritedOption, 0, len(reversed))
+	for i := len(reversed) - 1; i >= 0; i-- {
+		result = append(result, reversed[i])
+	}
+	return result
+}
+
+// placementAt finds the nearest PersistentPlacement at or above depth
+func placementAt(chain []*Command, depth int) string {
+	for i := depth; i >= 0; i-- {
+		if chain[i].PersistentPlacement != "" {
+			return chain[i].PersistentPlacement
+		}
+	}
+	return PlacementAfter
+}
@@ -0,0 +1,119 @@
+// Package importhelp builds a schema by running a CLI's --help
+// and parsing the output
+package importhelp
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/xhd2015/cli2web/config"
+)
+
+// DefaultDepth is the default subcommand recursion limit
+const DefaultDepth = 5
+
+type Options struct {
+	// Depth limits how many levels of subcommands are visited,
+	// 0 only imports the root command
+	Depth int
+	// RunHelp returns the help output of args, defaults
+	// to executing `args... --help`. A command that exits
+	// non-zero gives its output and an *exec.ExitError.
+	RunHelp func(args []string) (string, error)
+}
+
+// Import runs `<args> --help`, then `<args> <sub> --help` for each
+// listed subcommand recursively, and converts the output into a schema.
+// The root is the executable, leading words after it become the
+// commands the imported one is nested in, so `kool git` gives
+// kool > git.
+func Import(args []string, opts Options) (*config.Schema, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("requires command")
+	}
+	if opts.RunHelp == nil {
+		opts.RunHelp = runHelp
+	}
+	root, err := importCommand(args, nil, opts.Depth, opts.RunHelp)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(args) - 2; i >= 0; i-- {
+		root = &config.Command{
+			Name:     args[i],
+			Commands: []*config.Command{root},
+		}
+	}
+	root.Name = filepath.Base(args[0])
+	return root, nil
+}
+
+// importCommand imports the command at args, ancestors are
+// the already imported commands above it
+func importCommand(args []string, ancestors []*config.Command, depth int, runHelp func(args []string) (string, error)) (*config.Command, error) {
+	output, err := runHelp(args)
+	help := Parse(output)
+	if err != nil {
+		// some tools exit non-zero after printing help, others
+		// print an error such as "unrecognized command: x"
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || !help.hasContent() {
+			return nil, fmt.Errorf("running %v --help: %w", args, err)
+		}
+	}
+
+	cmd := &config.Command{
+		Name:        args[len(args)-1],
+		Description: help.Description,
+		Options:     help.Options,
+		Arguments:   help.Arguments,
+	}
+	markPersistent(ancestors, help.GlobalOptions)
+	if depth <= 0 {
+		return cmd, nil
+	}
+
+	chain := append(ancestors[:len(ancestors):len(ancestors)], cmd)
+	for _, sub := range help.Commands {
+		subArgs := append(args[:len(args):len(args)], sub.Name)
+		subCmd, err := importCommand(subArgs, chain, depth-1, runHelp)
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// listed, but not a command that has help,
+			// e.g. a help topic
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if subCmd.Description == "" {
+			subCmd.Description = sub.Description
+		}
+		cmd.Commands = append(cmd.Commands, subCmd)
+	}
+	return cmd, nil
+}
+
+// markPersistent marks the ancestors' options that a subcommand
+// lists as inherited
+func markPersistent(ancestors []*config.Command, globalOptions []*config.Option) {
+	for _, global := range globalOptions {
+		name := global.Spec().Name
+		for _, ancestor := range ancestors {
+			for _, opt := range ancestor.Options {
+				if opt.Spec().Name == name {
+					opt.Persistent = true
+				}
+			}
+		}
+	}
+}
+
+func runHelp(args []string) (string, error) {
+	cmd := exec.Command(args[0], append(args[1:len(args):len(args)], "--help")...)
+	// many tools print help to stderr
+	output, err := cmd.CombinedOutput()
+	return string(output), err
+}
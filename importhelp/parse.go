@@ -0,0 +1,406 @@
+package importhelp
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/xhd2015/cli2web/config"
+)
+
+// Help is the information extracted from a single --help output
+type Help struct {
+	// Usage is the first line of the usage section
+	Usage       string
+	Description string
+	Options     []*config.Option
+	Arguments   []*config.Argument
+	// Commands only carry name and description
+	Commands []*config.Command
+	// GlobalOptions are inherited options listed separately,
+	// e.g. cobra's "Global Flags"
+	GlobalOptions []*config.Option
+}
+
+type sectionKind int
+
+const (
+	sectionUnknown sectionKind = iota
+	sectionUsage
+	sectionDescription
+	sectionName
+	sectionCommands
+	sectionOptions
+	sectionGlobalOptions
+	sectionArguments
+	sectionIgnored
+)
+
+type section struct {
+	kind  sectionKind
+	lines []string
+}
+
+type entry struct {
+	term        string
+	description string
+	// children are deeper indented entries, used by
+	// argparse's {a,b} subcommand lists
+	children []*entry
+}
+
+// headerRegex matches section headers like "Flags:", "GLOBAL OPTIONS:",
+// "Usage of kool:" and "Usage: kool [flags]"
+var headerRegex = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9 ()/_-]*):(\s.*)?$`)
+
+// Parse extracts description, options, arguments and subcommands from help
+// text in the layouts of Go flag, cobra, urfave/cli, argparse, clap and GNU getopt
+func Parse(text string) *Help {
+	help := &Help{}
+	sections := splitSections(text)
+
+	var descriptionLines []string
+	// the description precedes options, commands and arguments,
+	// free text after them is usually a footer
+	var seenEntries bool
+	for _, sec := range sections {
+		switch sec.kind {
+		case sectionUnknown:
+			// free text is the description, indented
+			// dash lines are GNU style options
+			var optionLines []string
+			var inOption bool
+			for _, line := range sec.lines {
+				trimmed := strings.TrimSpace(line)
+				if indentOf(line) > 0 && (strings.HasPrefix(trimmed, "-") || inOption && trimmed != "") {
+					inOption = true
+					optionLines = append(optionLines, line)
+					continue
+				}
+				inOption = false
+				if !seenEntries {
+					descriptionLines = append(descriptionLines, line)
+				}
+			}
+			if len(optionLines) > 0 {
+				seenEntries = true
+				help.Options = append(help.Options, parseOptions(parseEntries(optionLines))...)
+			}
+		case sectionUsage:
+			for _, line := range sec.lines {
+				if trimmed := strings.TrimSpace(line); trimmed != "" && help.Usage == "" {
+					help.Usage = trimmed
+				}
+			}
+		case sectionDescription:
+			descriptionLines = append(descriptionLines, sec.lines...)
+		case sectionName:
+			// urfave/cli: "kool - the kool tool"
+			for _, line := range sec.lines {
+				if idx := strings.Index(line, " - "); idx >= 0 && help.Description == "" {
+					help.Description = strings.TrimSpace(line[idx+3:])
+				}
+			}
+		case sectionCommands:
+			seenEntries = true
+			help.Commands = append(help.Commands, parseCommands(parseEntries(sec.lines))...)
+		case sectionOptions:
+			seenEntries = true
+			help.Options = append(help.Options, parseOptions(parseEntries(sec.lines))...)
+		case sectionGlobalOptions:
+			seenEntries = true
+			help.GlobalOptions = append(help.GlobalOptions, parseOptions(parseEntries(sec.lines))...)
+		case sectionArguments:
+			seenEntries = true
+			for _, e := range parseEntries(sec.lines) {
+				if isChoiceList(e.term) {
+					// argparse subcommands
+					help.Commands = append(help.Commands, parseCommands(e.children)...)
+					continue
+				}
+				help.Arguments = append(help.Arguments, parseArgument(e))
+			}
+		}
+	}
+	if help.Description == "" {
+		help.Description = firstParagraph(descriptionLines)
+	}
+
+	// urfave/cli lists the root's own options as GLOBAL OPTIONS
+	if len(help.Options) == 0 {
+		help.Options, help.GlobalOptions = help.GlobalOptions, nil
+	}
+	return help
+}
+
+// hasContent tells if the text looked like help: it has a usage
+// line, options, arguments or commands. An error message such as
+// "unrecognized command: x" has none of them.
+func (h *Help) hasContent() bool {
+	return h.Usage != "" || len(h.Options) > 0 || len(h.GlobalOptions) > 0 ||
+		len(h.Arguments) > 0 || len(h.Commands) > 0
+}
+
+func splitSections(text string) []*section {
+	lines := strings.Split(expandTabs(strings.ReplaceAll(text, "\r\n", "\n")), "\n")
+	current := &section{kind: sectionUnknown}
+	sections := []*section{current}
+	for _, line := range lines {
+		if indentOf(line) == 0 && strings.TrimSpace(line) != "" {
+			if m := headerRegex.FindStringSubmatch(line); m != nil {
+				kind := classifyHeader(m[1])
+				inline := strings.TrimSpace(m[2])
+				// only "Usage: kool [flags]" has content on the header line,
+				// other sentences with a colon are plain text
+				if inline == "" || kind == sectionUsage {
+					current = &section{kind: kind}
+					sections = append(sections, current)
+					if inline != "" {
+						current.lines = append(current.lines, inline)
+					}
+					continue
+				}
+			}
+			if current.kind != sectionUnknown {
+				// section content is indented, this is free text
+				current = &section{kind: sectionUnknown}
+				sections = append(sections, current)
+			}
+		}
+		current.lines = append(current.lines, line)
+	}
+	return sections
+}
+
+func classifyHeader(header string) sectionKind {
+	h := strings.ToLower(strings.TrimSpace(header))
+	switch {
+	case strings.HasPrefix(h, "usage of"):
+		// Go flag prints only options after "Usage of kool:"
+		return sectionOptions
+	case h == "usage":
+		return sectionUsage
+	case h == "description":
+		return sectionDescription
+	case h == "name":
+		return sectionName
+	case strings.Contains(h, "help topic"):
+		return sectionIgnored
+	case strings.Contains(h, "global") || strings.Contains(h, "inherited"):
+		return sectionGlobalOptions
+	case strings.Contains(h, "command"):
+		return sectionCommands
+	case strings.Contains(h, "option") || strings.Contains(h, "flag"):
+		// argparse's "optional arguments" included
+		return sectionOptions
+	case strings.Contains(h, "argument") || h == "args":
+		return sectionArguments
+	case h == "examples" || h == "example" || h == "aliases" || h == "version" ||
+		h == "author" || h == "authors" || h == "copyright":
+		return sectionIgnored
+	}
+	return sectionUnknown
+}
+
+// parseEntries splits indented lines into term/description entries,
+// deeper indented lines continue the previous entry
+func parseEntries(lines []string) []*entry {
+	var entries []*entry
+	baseIndent := -1
+	var last *entry
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := indentOf(line)
+		if baseIndent < 0 {
+			baseIndent = indent
+		}
+		content := strings.TrimSpace(line)
+		// cobra and GNU indent long-only flags further
+		// than short ones, they still start new entries
+		if indent > baseIndent && last != nil && !strings.HasPrefix(content, "-") {
+			if isChoiceList(last.term) {
+				term, desc := splitTerm(content)
+				last.children = append(last.children, &entry{term: term, description: desc})
+				continue
+			}
+			// wrapped descriptions, Go flag puts them on the next line
+			last.description = joinText(last.description, content)
+			continue
+		}
+		term, desc := splitTerm(content)
+		last = &entry{term: term, description: desc}
+		entries = append(entries, last)
+	}
+	return entries
+}
+
+// splitTerm splits "-f, --format <fmt>   output format" at the
+// first run of two or more spaces
+var termSepRegex = regexp.MustCompile(`\s{2,}`)
+
+func splitTerm(content string) (string, string) {
+	loc := termSepRegex.FindStringIndex(content)
+	if loc == nil {
+		return content, ""
+	}
+	return content[:loc[0]], strings.TrimSpace(content[loc[1]:])
+}
+
+func parseCommands(entries []*entry) []*config.Command {
+	var commands []*config.Command
+	for _, e := range entries {
+		// "status, st" in urfave/cli, "status (st)" elsewhere
+		name := strings.FieldsFunc(e.term, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '('
+		})
+		if len(name) == 0 || name[0] == "help" || strings.HasPrefix(name[0], "-") {
+			continue
+		}
+		commands = append(commands, &config.Command{
+			Name:        name[0],
+			Description: e.description,
+		})
+	}
+	return commands
+}
+
+var (
+	defaultRegexes = []*regexp.Regexp{
+		// cobra, Go flag: (default "x"), (default 5)
+		regexp.MustCompile(`\s*\(default:?\s+"?([^")]*)"?\)`),
+		// clap: [default: x]
+		regexp.MustCompile(`\s*\[default:\s*([^\]]*)\]`),
+		// argparse convention: (default: x)
+		regexp.MustCompile(`\s*\(default:\s*([^)]*)\)`),
+	}
+	choiceListRegex    = regexp.MustCompile(`\{[^}]*\}`)
+	numberPlaceholders = map[string]bool{
+		"int": true, "int64": true, "uint": true, "uint64": true,
+		"float": true, "float64": true, "n": true, "num": true,
+		"number": true, "count": true,
+	}
+)
+
+func parseOptions(entries []*entry) []*config.Option {
+	var options []*config.Option
+	for _, e := range entries {
+		if !strings.HasPrefix(e.term, "-") {
+			continue
+		}
+		// --color[=WHEN] -> --color [WHEN]
+		term := strings.ReplaceAll(e.term, "[=", " [")
+		// argparse choices: --color {auto,always} -> --color <auto/always>
+		var choices []string
+		term = choiceListRegex.ReplaceAllStringFunc(term, func(list string) string {
+			choices = nil
+			for _, choice := range strings.Split(strings.Trim(list, "{}"), ",") {
+				choices = append(choices, strings.TrimSpace(choice))
+			}
+			return "<" + strings.Join(choices, "/") + ">"
+		})
+		spec := config.ParseFlags(term)
+		if spec.Name == "" || spec.Name == "--help" || spec.Name == "-help" || spec.Name == "-h" {
+			continue
+		}
+
+		opt := &config.Option{
+			Flags:       spec.Display(),
+			Description: e.description,
+			Type:        config.TypeBoolean,
+			Choices:     choices,
+		}
+		if spec.Placeholder != "" {
+			opt.Type = config.TypeString
+			if numberPlaceholders[strings.ToLower(spec.Placeholder)] {
+				opt.Type = config.TypeNumber
+			}
+		}
+		description, def := extractDefault(opt.Description)
+		// booleans only have meaningful defaults when true,
+		// argparse style "(default: find the max)" stays in the description
+		if opt.Type != config.TypeBoolean || def == "true" || def == "false" {
+			opt.Description = description
+			if def != "false" {
+				opt.Default = def
+			}
+		}
+		options = append(options, opt)
+	}
+	return options
+}
+
+func parseArgument(e *entry) *config.Argument {
+	name := strings.TrimSuffix(e.term, "...")
+	name = strings.Trim(name, "<>[]")
+	description, def := extractDefault(e.description)
+	return &config.Argument{
+		Name:        name,
+		Description: description,
+		Type:        config.TypeString,
+		Default:     def,
+	}
+}
+
+func extractDefault(description string) (string, string) {
+	for _, re := range defaultRegexes {
+		if m := re.FindStringSubmatchIndex(description); m != nil {
+			def := description[m[2]:m[3]]
+			return strings.TrimSpace(description[:m[0]] + description[m[1]:]), def
+		}
+	}
+	return description, ""
+}
+
+func isChoiceList(term string) bool {
+	return strings.HasPrefix(term, "{") && strings.HasSuffix(term, "}")
+}
+
+func firstParagraph(lines []string) string {
+	var paragraph []string
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			if len(paragraph) > 0 {
+				break
+			}
+			continue
+		}
+		paragraph = append(paragraph, trimmed)
+	}
+	return strings.Join(paragraph, " ")
+}
+
+func joinText(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + " " + b
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+func expandTabs(text string) string {
+	if !strings.Contains(text, "\t") {
+		return text
+	}
+	var sb strings.Builder
+	col := 0
+	for _, r := range text {
+		switch r {
+		case '\t':
+			n := 8 - col%8
+			sb.WriteString(strings.Repeat(" ", n))
+			col += n
+		case '\n':
+			sb.WriteRune(r)
+			col = 0
+		default:
+			sb.WriteRune(r)
+			col++
+		}
+	}
+	return sb.String()
+}
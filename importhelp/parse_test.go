@@ -0,0 +1,265 @@
+package importhelp
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/cli2web/config"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		file          string
+		description   string
+		options       []string
+		globalOptions []string
+		arguments     []string
+		commands      []string
+	}{
+		{
+			file: "go-flag.txt",
+			options: []string{
+				`-count <int>|number|3|number of runs`,
+				`-name <string>|string|world|the name to greet`,
+				`-timeout <duration>|string|1s|how long to wait`,
+				`-v|boolean||verbose output`,
+			},
+		},
+		{
+			file:        "cobra.txt",
+			description: "Kool is a collection of handy developer utilities.",
+			options: []string{
+				`-c, --config <string>|string|$HOME/.kool.yaml|config file`,
+				`--verbose|boolean||enable verbose output`,
+			},
+			commands: []string{
+				"completion|Generate the autocompletion script for the specified shell",
+				"git|Git commands",
+			},
+		},
+		{
+			file:        "cobra-sub.txt",
+			description: "Get the next git tag",
+			options: []string{
+				`-p, --push|boolean||push the tag to remote`,
+				`--prefix <string>|string||tag prefix`,
+			},
+			globalOptions: []string{
+				`-c, --config <string>|string|$HOME/.kool.yaml|config file`,
+				`--verbose|boolean||enable verbose output`,
+			},
+		},
+		{
+			file:        "urfave.txt",
+			description: "the kool tool",
+			options: []string{
+				`--format, -f <value>|string|text|output format`,
+				`--dry-run|boolean||print without executing`,
+			},
+			commands: []string{"git|Git commands"},
+		},
+		{
+			file:        "argparse.txt",
+			description: "Run kool tasks.",
+			options: []string{
+				`--jobs <N>|number|4|number of parallel jobs`,
+				`--color <auto/always/never>|string||when to use colors|auto,always,never`,
+			},
+			commands: []string{"init|initialize a project", "run|run a task"},
+		},
+		{
+			file: "argparse-args.txt",
+			options: []string{
+				`--sum|boolean||sum the results (default: find the max)`,
+			},
+			arguments: []string{"task|string||the tasks to run"},
+		},
+		{
+			file:        "clap.txt",
+			description: "Clones a repository",
+			options: []string{
+				`-d, --depth <DEPTH>|string|1|Truncate history to this depth`,
+				`-q, --quiet|boolean||Suppress output`,
+			},
+			arguments: []string{"REMOTE|string||The remote to clone"},
+		},
+		{
+			file:        "gnu.txt",
+			description: "List information about the FILEs (the current directory by default). Sort entries alphabetically if none of -cftuvSUX nor --sort is specified.",
+			options: []string{
+				`-a, --all|boolean||do not ignore entries starting with .`,
+				`--block-size <SIZE>|string||with -l, scale sizes by SIZE when printing them; e.g., '--block-size=M'; see SIZE format below`,
+				`--color [WHEN]|string||color the output WHEN; more info below`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			help := Parse(readTestdata(t, tt.file))
+			if help.Description != tt.description {
+				t.Errorf("Description = %q, expected %q", help.Description, tt.description)
+			}
+			compareLines(t, "Options", formatOptions(help.Options), tt.options)
+			compareLines(t, "GlobalOptions", formatOptions(help.GlobalOptions), tt.globalOptions)
+			compareLines(t, "Arguments", formatArguments(help.Arguments), tt.arguments)
+			compareLines(t, "Commands", formatCommands(help.Commands), tt.commands)
+		})
+	}
+}
+
+func TestImport(t *testing.T) {
+	helps := map[string]string{
+		"kool":              readTestdata(t, "cobra.txt"),
+		"kool git":          "Git commands\n\nAvailable Commands:\n  tag-next    Get the next git tag\n",
+		"kool git tag-next": readTestdata(t, "cobra-sub.txt"),
+		"kool completion":   "Generate the autocompletion script\n",
+	}
+	runHelp := func(args []string) (string, error) {
+		key := strings.Join(append([]string{filepath.Base(args[0])}, args[1:]...), " ")
+		help, ok := helps[key]
+		if !ok {
+			return "", fmt.Errorf("unexpected command: %v", args)
+		}
+		return help, nil
+	}
+
+	schema, err := Import([]string{"/usr/local/bin/kool"}, Options{Depth: 1, RunHelp: runHelp})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if schema.Name != "kool" {
+		t.Errorf("Name = %q, expected kool", schema.Name)
+	}
+	if len(schema.Commands) != 2 || len(schema.Commands[1].Commands) != 0 {
+		t.Fatalf("Expected 2 commands without subcommands at depth 1, got %+v", schema.Commands)
+	}
+
+	schema, err = Import([]string{"kool"}, Options{Depth: 2, RunHelp: runHelp})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	git := schema.Commands[1]
+	if len(git.Commands) != 1 || git.Commands[0].Name != "tag-next" {
+		t.Fatalf("Expected git tag-next, got %+v", git.Commands)
+	}
+	// listed as Global Flags by tag-next
+	for _, opt := range schema.Options {
+		if !opt.Persistent {
+			t.Errorf("Expected %s to be persistent", opt.Flags)
+		}
+	}
+}
+
+func TestImport_Subcommand(t *testing.T) {
+	helps := map[string]string{
+		"kool git":          "Git commands\n\nAvailable Commands:\n  tag-next    Get the next git tag\n",
+		"kool git tag-next": readTestdata(t, "cobra-sub.txt"),
+	}
+	runHelp := func(args []string) (string, error) {
+		key := strings.Join(append([]string{filepath.Base(args[0])}, args[1:]...), " ")
+		help, ok := helps[key]
+		if !ok {
+			return "", fmt.Errorf("unexpected command: %v", args)
+		}
+		return help, nil
+	}
+
+	schema, err := Import([]string{"/usr/local/bin/kool", "git"}, Options{Depth: 1, RunHelp: runHelp})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	// the root is the executable, git is nested in it
+	if schema.Name != "kool" || len(schema.Commands) != 1 {
+		t.Fatalf("Expected root kool with one command, got %+v", schema)
+	}
+	git := schema.Commands[0]
+	if git.Name != "git" || git.Description != "Git commands" {
+		t.Errorf("Expected the imported git command, got %+v", git)
+	}
+	if len(git.Commands) != 1 || git.Commands[0].Name != "tag-next" {
+		t.Errorf("Expected git tag-next, got %+v", git.Commands)
+	}
+}
+
+func TestImport_ExitStatus(t *testing.T) {
+	type result struct {
+		output string
+		failed bool
+	}
+	results := map[string]result{
+		// help printed with a non-zero exit status is kept
+		"kool":     {"Usage:\n  kool [command]\n\nAvailable Commands:\n  git     Git commands\n  topics  Help topics\n", true},
+		"kool git": {"Git commands\n\nUsage:\n  kool git [flags]\n", false},
+		// not a command, it prints an error
+		"kool topics": {"unrecognized command: topics\n", true},
+	}
+	runHelp := func(args []string) (string, error) {
+		r := results[strings.Join(args, " ")]
+		if r.failed {
+			return r.output, &exec.ExitError{}
+		}
+		return r.output, nil
+	}
+
+	schema, err := Import([]string{"kool"}, Options{Depth: 1, RunHelp: runHelp})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if len(schema.Commands) != 1 || schema.Commands[0].Name != "git" || schema.Commands[0].Description != "Git commands" {
+		t.Errorf("Expected only git, got %+v", schema.Commands)
+	}
+
+	results["kool"] = result{"unrecognized command: kool\n", true}
+	if _, err := Import([]string{"kool"}, Options{Depth: 1, RunHelp: runHelp}); err == nil {
+		t.Errorf("Expected an error for a root command without help")
+	}
+}
+
+func readTestdata(t *testing.T, file string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join("testdata", file))
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", file, err)
+	}
+	return string(content)
+}
+
+func compareLines(t *testing.T, what string, actual []string, expected []string) {
+	t.Helper()
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%s =\n  %s\nexpected\n  %s", what, strings.Join(actual, "\n  "), strings.Join(expected, "\n  "))
+	}
+}
+
+func formatOptions(options []*config.Option) []string {
+	var lines []string
+	for _, opt := range options {
+		line := strings.Join([]string{opt.Flags, opt.Type, opt.Default, opt.Description}, "|")
+		if len(opt.Choices) > 0 {
+			line += "|" + strings.Join(opt.Choices, ",")
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func formatArguments(arguments []*config.Argument) []string {
+	var lines []string
+	for _, arg := range arguments {
+		lines = append(lines, strings.Join([]string{arg.Name, arg.Type, arg.Default, arg.Description}, "|"))
+	}
+	return lines
+}
+
+func formatCommands(commands []*config.Command) []string {
+	var lines []string
+	for _, cmd := range commands {
+		lines = append(lines, cmd.Name+"|"+cmd.Description)
+	}
+	return lines
+}
@@ -1,13 +1,39 @@
 package markjson
 
 import (
+	"fmt"
+	"regexp"
 	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
 )
 
 type Section struct {
 	// e.g. Arguments
-	Title    string   `json:"title"`
+	Title string `json:"title"`
+	// Level is the number of '#' of the header, 1 for "# Arguments"
+	Level    int      `json:"level"`
 	Snippets Snippets `json:"snippets"`
+	// Children are the sections with a deeper level that follow,
+	// only filled by ParseTree
+	Children Sections `json:"children,omitempty"`
+
+	// Start is the header, End is the end of the last non-blank line
+	// before the next header
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Position is a 1-based line and column in the markdown content,
+// columns count bytes
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
 }
 
 type SnippetType string
@@ -15,73 +41,257 @@ type SnippetType string
 const (
 	Text SnippetType = "text"
 	Code SnippetType = "code"
+	// Table is a GFM table, see Snippet.Rows
+	Table SnippetType = "table"
+	// List is a bullet or ordered list, see Snippet.Items
+	List SnippetType = "list"
+	// DefinitionList is a list of "term" lines each followed
+	// by ": description" lines, see Snippet.Definitions
+	DefinitionList SnippetType = "definitions"
 )
 
 type Snippet struct {
 	Type     SnippetType `json:"type"`
 	Language string      `json:"language,omitempty"` // effective when Type == "code", can be json, and other things
 	Content  string      `json:"content"`
+
+	// Columns are the header cells of a table, each of Rows
+	// maps a column to its cell
+	Columns []string            `json:"columns,omitempty"`
+	Rows    []map[string]string `json:"rows,omitempty"`
+	// Items are the list items without markers, continuation
+	// lines are joined with "\n"
+	Items       []string      `json:"items,omitempty"`
+	Definitions []*Definition `json:"definitions,omitempty"`
+
+	// Start and End enclose Content, for code it is
+	// the lines between the fences
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+
+	// indents are the bytes before each line of Content
+	// that are not part of it
+	indents []int
+}
+
+// Definition is a term of a definition list, descriptions of
+// multiple ": " lines are joined with "\n"
+type Definition struct {
+	Term        string `json:"term"`
+	Description string `json:"description"`
+}
+
+// PositionOf maps a byte offset in Content to its
+// position in the markdown content
+func (s *Snippet) PositionOf(offset int) Position {
+	if offset > len(s.Content) {
+		offset = len(s.Content)
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	line := strings.Count(s.Content[:offset], "\n")
+	column := offset - (strings.LastIndex(s.Content[:offset], "\n") + 1) + 1
+	if line < len(s.indents) {
+		column += s.indents[line]
+	}
+	return Position{Line: s.Start.Line + line, Column: column}
 }
 
 type Sections []*Section
 
 type Snippets []*Snippet
 
-// Parse extracts all sections from markdown content
-func Parse(content string) (Sections, error) {
-	var sections Sections
+// Document is a markdown file with its front matter
+type Document struct {
+	// FrontMatter is decoded from a leading --- YAML or +++ TOML
+	// block, nil if there is none
+	FrontMatter map[string]interface{} `json:"frontMatter,omitempty"`
+	Sections    Sections               `json:"sections"`
+
+	// preamble is the content before the first section, front
+	// matter included, as written, kept by Render
+	preamble string
+}
 
-	// Split content into lines for processing
+// ParseDocument extracts the front matter and all sections
+// from markdown content
+func ParseDocument(content string) (*Document, error) {
 	lines := strings.Split(content, "\n")
+	frontMatter, start, err := parseFrontMatter(lines)
+	if err != nil {
+		return nil, err
+	}
+	doc := &Document{
+		FrontMatter: frontMatter,
+		Sections:    parseSections(lines[start:], start+1),
+	}
+	end := len(lines)
+	if len(doc.Sections) > 0 {
+		end = doc.Sections[0].Start.Line - 1
+	}
+	if preamble := strings.TrimRight(strings.Join(lines[:end], "\n"), " \t\r\n"); preamble != "" {
+		doc.preamble = preamble + "\n"
+	}
+	return doc, nil
+}
+
+// Parse extracts all sections from markdown content,
+// front matter is skipped
+func Parse(content string) (Sections, error) {
+	doc, err := ParseDocument(content)
+	if err != nil {
+		return nil, err
+	}
+	return doc.Sections, nil
+}
+
+// parseFrontMatter decodes the front matter at the start of lines,
+// start is the index of the first line after it. A --- block that
+// does not decode to a YAML mapping is content, not front matter.
+func parseFrontMatter(lines []string) (frontMatter map[string]interface{}, start int, err error) {
+	if len(lines) == 0 {
+		return nil, 0, nil
+	}
+	delimiter := strings.TrimSpace(strings.TrimPrefix(lines[0], "\ufeff"))
+	if delimiter != "---" && delimiter != "+++" {
+		return nil, 0, nil
+	}
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == delimiter {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		// not closed, a thematic break rather than front matter
+		return nil, 0, nil
+	}
+	raw := strings.Join(lines[1:end], "\n")
+	frontMatter = make(map[string]interface{})
+	if delimiter == "+++" {
+		if _, err := toml.Decode(raw, &frontMatter); err != nil {
+			return nil, 0, fmt.Errorf("failed to parse TOML front matter: %w", err)
+		}
+	} else if err := yaml.Unmarshal([]byte(raw), &frontMatter); err != nil {
+		// not a mapping, e.g. text between two thematic breaks
+		return nil, 0, nil
+	}
+	if frontMatter == nil {
+		// yaml leaves the map nil for an empty block
+		frontMatter = make(map[string]interface{})
+	}
+	return frontMatter, end + 1, nil
+}
+
+// parseSections extracts all sections from markdown lines,
+// firstLine is the line number of lines[0]
+func parseSections(lines []string, firstLine int) Sections {
+	var sections Sections
 
 	var currentSection *Section
-	var currentSnippets Snippets
 	var currentSnippet *Snippet
 	var inCodeBlock bool
-	var codeBlockLanguage string
+	var openFence fence
 	var codeLines []string
+	var codeIndents []int
 	var textLines []string
+	var textStart int
+	var lastLine int
 
-	for _, line := range lines {
-		// Check if this line is a section header (starts with #)
-		if strings.HasPrefix(strings.TrimSpace(line), "#") {
-			// Save previous section if exists
-			if currentSection != nil {
-				// Add any pending snippet
-				if currentSnippet != nil {
-					if currentSnippet.Type == Code {
-						currentSnippet.Content = strings.Join(codeLines, "\n")
-					} else {
-						currentSnippet.Content = strings.TrimSpace(strings.Join(textLines, "\n"))
-					}
-					currentSnippets = append(currentSnippets, currentSnippet)
-				} else if len(textLines) > 0 {
-					// Handle accumulated text lines without a current snippet
-					textContent := strings.TrimSpace(strings.Join(textLines, "\n"))
-					if textContent != "" {
-						snippet := &Snippet{
-							Type:    Text,
-							Content: textContent,
-						}
-						currentSnippets = append(currentSnippets, snippet)
-					}
-				}
+	// flushText adds the pending text lines as snippets
+	flushText := func() {
+		currentSection.Snippets = append(currentSection.Snippets, parseTextBlocks(textLines, textStart)...)
+		textLines = nil
+	}
+	flushCode := func() {
+		if currentSnippet == nil {
+			return
+		}
+		currentSnippet.Content = strings.Join(codeLines, "\n")
+		currentSnippet.indents = codeIndents
+		currentSnippet.End = currentSnippet.Start
+		if n := len(codeLines); n > 0 {
+			currentSnippet.End = Position{
+				Line:   currentSnippet.Start.Line + n - 1,
+				Column: codeIndents[n-1] + len(codeLines[n-1]) + 1,
+			}
+		}
+		currentSection.Snippets = append(currentSection.Snippets, currentSnippet)
+		currentSnippet = nil
+	}
+	closeSection := func() {
+		if currentSection == nil {
+			return
+		}
+		if inCodeBlock {
+			// unclosed code block
+			flushCode()
+		}
+		flushText()
+		currentSection.End = Position{Line: lastLine, Column: len(lines[lastLine-firstLine]) + 1}
+		sections = append(sections, currentSection)
+	}
+
+	for i, line := range lines {
+		lineNo := firstLine + i
+		blank := strings.TrimSpace(line) == ""
 
-				currentSection.Snippets = currentSnippets
-				sections = append(sections, currentSection)
+		// Inside a code block only the closing fence is special,
+		// so that '#' comments in examples are kept as code
+		if inCodeBlock {
+			if !blank && currentSection != nil {
+				lastLine = lineNo
+			}
+			if f, info, ok := parseFence(line); ok && f.closes(openFence) && info == "" {
+				inCodeBlock = false
+				if currentSection != nil {
+					flushCode()
+				}
+				continue
 			}
+			if currentSection != nil {
+				code := trimIndent(line, openFence.indent)
+				codeLines = append(codeLines, code)
+				codeIndents = append(codeIndents, len(line)-len(code))
+			}
+			continue
+		}
 
-			// Start new section
-			title := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "#"))
+		// Check if this line is a section header (starts with #)
+		header := strings.TrimSpace(line)
+		if strings.HasPrefix(header, "#") {
+			closeSection()
+			title := strings.TrimLeft(header, "#")
 			currentSection = &Section{
-				Title:    title,
+				Title:    strings.TrimSpace(title),
+				Level:    len(header) - len(title),
 				Snippets: []*Snippet{},
+				Start:    Position{Line: lineNo, Column: len(line) - len(strings.TrimLeft(line, " \t")) + 1},
+			}
+			lastLine = lineNo
+			continue
+		}
+		if !blank && currentSection != nil {
+			lastLine = lineNo
+		}
+
+		// Check for code block start
+		if f, info, ok := parseFence(line); ok {
+			inCodeBlock = true
+			openFence = f
+			if currentSection == nil {
+				continue
+			}
+			flushText()
+			codeLines = nil
+			codeIndents = nil
+			currentSnippet = &Snippet{
+				Type:     Code,
+				Language: info,
+				Start:    Position{Line: lineNo + 1, Column: f.indent + 1},
 			}
-			currentSnippets = []*Snippet{}
-			currentSnippet = nil
-			inCodeBlock = false
-			codeLines = []string{}
-			textLines = []string{}
 			continue
 		}
 
@@ -89,81 +299,327 @@ func Parse(content string) (Sections, error) {
 		if currentSection == nil {
 			continue
 		}
+		if len(textLines) == 0 {
+			textStart = lineNo
+		}
+		textLines = append(textLines, line)
+	}
+	closeSection()
 
-		// Check for code block start/end
-		trimmedLine := strings.TrimSpace(line)
-		if strings.HasPrefix(trimmedLine, "```") {
-			if !inCodeBlock {
-				// Starting code block
-				// Save any pending text snippet
-				if len(textLines) > 0 {
-					textContent := strings.TrimSpace(strings.Join(textLines, "\n"))
-					if textContent != "" {
-						snippet := &Snippet{
-							Type:    Text,
-							Content: textContent,
-						}
-						currentSnippets = append(currentSnippets, snippet)
-					}
-					textLines = []string{}
-				}
+	return sections
+}
 
-				// Extract language from code block marker
-				codeBlockLanguage = strings.TrimSpace(strings.TrimPrefix(trimmedLine, "```"))
-				inCodeBlock = true
-				codeLines = []string{}
-				currentSnippet = &Snippet{
-					Type:     Code,
-					Language: codeBlockLanguage,
-				}
-			} else {
-				// Ending code block
-				inCodeBlock = false
-				if currentSnippet != nil {
-					currentSnippet.Content = strings.Join(codeLines, "\n")
-					currentSnippets = append(currentSnippets, currentSnippet)
-					currentSnippet = nil
-				}
-				codeLines = []string{}
-			}
+var (
+	listItemRegex       = regexp.MustCompile(`^ {0,3}(?:[-*+]|\d{1,9}[.)])(?:[ \t]+|$)`)
+	tableDelimiterRegex = regexp.MustCompile(`^\|?\s*:?-+:?\s*(?:\|\s*:?-+:?\s*)*\|?$`)
+)
+
+// parseTextBlocks splits text lines starting at line firstLine
+// into tables, lists, definition lists and plain text
+func parseTextBlocks(lines []string, firstLine int) Snippets {
+	var snippets Snippets
+	var text []string
+	var textStart int
+	flush := func() {
+		if snippet := newTextSnippet(text, textStart); snippet != nil {
+			snippets = append(snippets, snippet)
+		}
+		text = nil
+	}
+	for i := 0; i < len(lines); {
+		var snippet *Snippet
+		var n int
+		if n = tableLength(lines[i:]); n > 0 {
+			snippet = newTable(lines[i:i+n], firstLine+i)
+		} else if n = listLength(lines[i:]); n > 0 {
+			snippet = newList(lines[i:i+n], firstLine+i)
+		} else if n = definitionListLength(lines[i:]); n > 0 && (i == 0 || strings.TrimSpace(lines[i-1]) == "") {
+			snippet = newDefinitionList(lines[i:i+n], firstLine+i)
+		}
+		if snippet != nil {
+			flush()
+			snippets = append(snippets, snippet)
+			i += n
 			continue
 		}
+		if len(text) == 0 {
+			textStart = firstLine + i
+		}
+		text = append(text, lines[i])
+		i++
+	}
+	flush()
+	return snippets
+}
 
-		// Collect content based on current state
-		if inCodeBlock {
-			codeLines = append(codeLines, line)
-		} else {
-			// Regular text line
-			textLines = append(textLines, line)
+// tableLength returns the number of lines of the table at the
+// start of lines, a header row followed by a delimiter row
+func tableLength(lines []string) int {
+	if len(lines) < 2 || !strings.Contains(lines[0], "|") || !tableDelimiterRegex.MatchString(strings.TrimSpace(lines[1])) {
+		return 0
+	}
+	if len(splitCells(lines[0])) != len(splitCells(lines[1])) {
+		return 0
+	}
+	n := 2
+	for n < len(lines) && strings.TrimSpace(lines[n]) != "" && strings.Contains(lines[n], "|") {
+		n++
+	}
+	return n
+}
+
+// splitCells splits a table row by unescaped pipes
+func splitCells(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
 		}
 	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
 
-	// Handle the last section
-	if currentSection != nil {
-		// Add any pending snippet
-		if currentSnippet != nil {
-			if currentSnippet.Type == Code {
-				currentSnippet.Content = strings.Join(codeLines, "\n")
+func newTable(lines []string, firstLine int) *Snippet {
+	snippet := newTextSnippet(lines, firstLine)
+	snippet.Type = Table
+	snippet.Columns = splitCells(lines[0])
+	for _, line := range lines[2:] {
+		cells := splitCells(line)
+		row := make(map[string]string, len(snippet.Columns))
+		for i, column := range snippet.Columns {
+			if i < len(cells) {
+				row[column] = cells[i]
 			} else {
-				currentSnippet.Content = strings.TrimSpace(strings.Join(textLines, "\n"))
+				row[column] = ""
 			}
-			currentSnippets = append(currentSnippets, currentSnippet)
-		} else if len(textLines) > 0 {
-			textContent := strings.TrimSpace(strings.Join(textLines, "\n"))
-			if textContent != "" {
-				snippet := &Snippet{
-					Type:    Text,
-					Content: textContent,
-				}
-				currentSnippets = append(currentSnippets, snippet)
+		}
+		snippet.Rows = append(snippet.Rows, row)
+	}
+	return snippet
+}
+
+// listLength returns the number of lines of the list at the start
+// of lines, items may continue on indented lines and be separated
+// by blank lines
+func listLength(lines []string) int {
+	if len(lines) == 0 || !listItemRegex.MatchString(lines[0]) {
+		return 0
+	}
+	n := 1
+	for n < len(lines) {
+		line := lines[n]
+		if strings.TrimSpace(line) == "" {
+			// a blank line ends the list unless it goes on
+			next := n + 1
+			for next < len(lines) && strings.TrimSpace(lines[next]) == "" {
+				next++
+			}
+			if next < len(lines) && (listItemRegex.MatchString(lines[next]) || isIndented(lines[next])) {
+				n = next
+				continue
 			}
+			break
 		}
+		if !listItemRegex.MatchString(line) && !isIndented(line) {
+			break
+		}
+		n++
+	}
+	return n
+}
 
-		currentSection.Snippets = currentSnippets
-		sections = append(sections, currentSection)
+func isIndented(line string) bool {
+	return strings.HasPrefix(line, "  ") || strings.HasPrefix(line, "\t")
+}
+
+func newList(lines []string, firstLine int) *Snippet {
+	snippet := newTextSnippet(lines, firstLine)
+	snippet.Type = List
+	for _, line := range lines {
+		if marker := listItemRegex.FindString(line); marker != "" {
+			snippet.Items = append(snippet.Items, strings.TrimSpace(line[len(marker):]))
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		last := len(snippet.Items) - 1
+		snippet.Items[last] += "\n" + strings.TrimSpace(line)
+	}
+	return snippet
+}
+
+// definitionListLength returns the number of lines of the definition
+// list at the start of lines, terms may be separated by blank lines
+func definitionListLength(lines []string) int {
+	isTerm := func(i int) bool {
+		return i+1 < len(lines) && strings.TrimSpace(lines[i]) != "" && !isDefinition(lines[i]) && isDefinition(lines[i+1])
+	}
+	n := 0
+	for isTerm(n) {
+		n += 2
+		for n < len(lines) && isDefinition(lines[n]) {
+			n++
+		}
+		end := n
+		for n < len(lines) && strings.TrimSpace(lines[n]) == "" {
+			n++
+		}
+		if !isTerm(n) {
+			return end
+		}
+	}
+	return n
+}
+
+func isDefinition(line string) bool {
+	return strings.HasPrefix(strings.TrimLeft(line, " "), ": ")
+}
+
+func newDefinitionList(lines []string, firstLine int) *Snippet {
+	snippet := newTextSnippet(lines, firstLine)
+	snippet.Type = DefinitionList
+	var current *Definition
+	for _, line := range lines {
+		switch {
+		case strings.TrimSpace(line) == "":
+		case isDefinition(line):
+			description := strings.TrimSpace(strings.TrimPrefix(strings.TrimLeft(line, " "), ":"))
+			if current.Description != "" {
+				description = current.Description + "\n" + description
+			}
+			current.Description = description
+		default:
+			current = &Definition{Term: strings.TrimSpace(line)}
+			snippet.Definitions = append(snippet.Definitions, current)
+		}
+	}
+	return snippet
+}
+
+// newTextSnippet joins text lines starting at line firstLine,
+// surrounding whitespace is trimmed, nil if there is no text
+func newTextSnippet(lines []string, firstLine int) *Snippet {
+	content := strings.TrimSpace(strings.Join(lines, "\n"))
+	if content == "" {
+		return nil
+	}
+	first := 0
+	for strings.TrimSpace(lines[first]) == "" {
+		first++
+	}
+	last := len(lines) - 1
+	for strings.TrimSpace(lines[last]) == "" {
+		last--
+	}
+	indents := make([]int, last-first+1)
+	indents[0] = len(lines[first]) - len(strings.TrimLeft(lines[first], " \t\r"))
+	return &Snippet{
+		Type:    Text,
+		Content: content,
+		Start:   Position{Line: firstLine + first, Column: indents[0] + 1},
+		End:     Position{Line: firstLine + last, Column: len(strings.TrimRight(lines[last], " \t\r")) + 1},
+		indents: indents,
+	}
+}
+
+// ParseTree extracts sections like Parse, nesting each section
+// under the closest preceding section with a lower level
+func ParseTree(content string) (Sections, error) {
+	sections, err := Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	return nest(sections), nil
+}
+
+// Tree returns the sections of the document nested like ParseTree.
+// The nested sections are copies, d.Sections stays flat.
+func (d *Document) Tree() Sections {
+	return nest(d.Sections)
+}
+
+// nest returns copies of sections with Children set, the
+// snippets are shared
+func nest(sections Sections) Sections {
+	var roots Sections
+	var stack Sections
+	for _, flat := range sections {
+		section := &Section{}
+		*section = *flat
+		section.Children = nil
+		for len(stack) > 0 && stack[len(stack)-1].Level >= section.Level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			roots = append(roots, section)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, section)
+		}
+		stack = append(stack, section)
+	}
+	return roots
+}
+
+// fence is a code fence marker like ``` or ~~~~
+type fence struct {
+	char   byte
+	length int
+	// indent is the number of spaces before the marker
+	indent int
+}
+
+// parseFence parses line as a code fence, info is the text
+// after the marker, e.g. the language of an opening fence
+func parseFence(line string) (f fence, info string, ok bool) {
+	trimmed := strings.TrimLeft(line, " \t")
+	if trimmed == "" || (trimmed[0] != '`' && trimmed[0] != '~') {
+		return fence{}, "", false
+	}
+	f.char = trimmed[0]
+	for f.length < len(trimmed) && trimmed[f.length] == f.char {
+		f.length++
 	}
+	if f.length < 3 {
+		return fence{}, "", false
+	}
+	info = strings.TrimSpace(trimmed[f.length:])
+	// ```a``` is inline code, not a fence
+	if f.char == '`' && strings.Contains(info, "`") {
+		return fence{}, "", false
+	}
+	f.indent = len(line) - len(trimmed)
+	return f, info, true
+}
 
-	return sections, nil
+// closes reports whether f closes the code block opened by open,
+// it needs the same character and at least the same length
+func (f fence) closes(open fence) bool {
+	return f.char == open.char && f.length >= open.length
+}
+
+// trimIndent removes up to n leading spaces, the indentation of
+// an indented fence is not part of the code
+func trimIndent(line string, n int) string {
+	i := 0
+	for i < n && i < len(line) && (line[i] == ' ' || line[i] == '\t') {
+		i++
+	}
+	return line[i:]
 }
 
 func (sections Sections) Find(title string) *Section {
@@ -184,10 +640,37 @@ func (snippets Snippets) FindJson() *Snippet {
 	return nil
 }
 
+// FindLanguage returns the first code snippet in one of the languages
+func (snippets Snippets) FindLanguage(languages ...string) *Snippet {
+	for _, snippet := range snippets {
+		if snippet.Type != Code {
+			continue
+		}
+		for _, language := range languages {
+			if snippet.Language == language {
+				return snippet
+			}
+		}
+	}
+	return nil
+}
+
+// FindType returns the first snippet of type t
+func (snippets Snippets) FindType(t SnippetType) *Snippet {
+	for _, snippet := range snippets {
+		if snippet.Type == t {
+			return snippet
+		}
+	}
+	return nil
+}
+
+// CombineAllTexts joins the content of all snippets but code,
+// tables and lists included as written
 func (snippets Snippets) CombineAllTexts() string {
 	var sb strings.Builder
 	for _, snippet := range snippets {
-		if snippet.Type == Text {
+		if snippet.Type != Code {
 			sb.WriteString(snippet.Content)
 			sb.WriteString("\n")
 		}
@@ -2,8 +2,11 @@ package markjson
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -53,6 +56,31 @@ func TestParseV2(t *testing.T) {
 			markdownFile: "nested-headers.md",
 			jsonFile:     "nested-headers.json",
 		},
+		{
+			name:         "Comments in code blocks",
+			markdownFile: "code-with-comments.md",
+			jsonFile:     "code-with-comments.json",
+		},
+		{
+			name:         "Tilde fence",
+			markdownFile: "tilde-fence.md",
+			jsonFile:     "tilde-fence.json",
+		},
+		{
+			name:         "Longer fence containing backticks",
+			markdownFile: "long-fence.md",
+			jsonFile:     "long-fence.json",
+		},
+		{
+			name:         "Tables, lists and definition lists",
+			markdownFile: "structured.md",
+			jsonFile:     "structured.json",
+		},
+		{
+			name:         "Indented fence",
+			markdownFile: "indented-fence.md",
+			jsonFile:     "indented-fence.json",
+		},
 	}
 
 	for _, tt := range tests {
@@ -110,6 +138,135 @@ func TestParseV2_EdgeCases(t *testing.T) {
 	})
 }
 
+func TestParseTree(t *testing.T) {
+	markdownContent, err := os.ReadFile(filepath.Join("testdata", "tree.md"))
+	if err != nil {
+		t.Fatalf("Failed to read markdown file %s: %v", "tree.md", err)
+	}
+	sections, err := ParseTree(string(markdownContent))
+	if err != nil {
+		t.Fatalf("ParseTree() error = %v", err)
+	}
+
+	var outline []string
+	var walk func(sections Sections, indent string)
+	walk = func(sections Sections, indent string) {
+		for _, section := range sections {
+			outline = append(outline, fmt.Sprintf("%s%s(%d)", indent, section.Title, section.Level))
+			walk(section.Children, indent+"  ")
+		}
+	}
+	walk(sections, "")
+	expected := []string{
+		"go(1)",
+		"  Options(2)",
+		"  replace(2)",
+		"    Options(3)",
+		"    Examples(3)",
+		"  mod(2)",
+		"    Deep(4)",
+		"    Arguments(3)",
+		"Other(1)",
+	}
+	if got := strings.Join(outline, "\n"); got != strings.Join(expected, "\n") {
+		t.Errorf("ParseTree() outline:\n%s\nexpected:\n%s", got, strings.Join(expected, "\n"))
+	}
+	if snippet := sections[0].Children[1].Children[1].Snippets.FindLanguage("sh"); snippet == nil || snippet.Content != "# replace a module\ngo mod edit -replace a=b" {
+		t.Errorf("expect the example of replace to be kept, got %+v", snippet)
+	}
+}
+
+func TestParseDocument(t *testing.T) {
+	tests := []struct {
+		markdownFile string
+		frontMatter  map[string]interface{}
+	}{
+		{
+			markdownFile: "front-matter-yaml.md",
+			frontMatter:  map[string]interface{}{"name": "tag-next", "aliases": []interface{}{"tn"}, "hidden": true},
+		},
+		{
+			markdownFile: "front-matter-toml.md",
+			frontMatter:  map[string]interface{}{"name": "tag-next", "aliases": []interface{}{"tn"}, "hidden": true},
+		},
+		{
+			// a leading --- without closing is not front matter
+			markdownFile: "front-matter-unclosed.md",
+		},
+		{
+			// thematic breaks around text that is not a mapping
+			markdownFile: "front-matter-thematic.md",
+		},
+		{
+			// not valid YAML, read as content
+			markdownFile: "front-matter-invalid.md",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.markdownFile, func(t *testing.T) {
+			markdownContent, err := os.ReadFile(filepath.Join("testdata", tt.markdownFile))
+			if err != nil {
+				t.Fatalf("Failed to read markdown file %s: %v", tt.markdownFile, err)
+			}
+			doc, err := ParseDocument(string(markdownContent))
+			if err != nil {
+				t.Fatalf("ParseDocument() error = %v", err)
+			}
+			if !reflect.DeepEqual(doc.FrontMatter, tt.frontMatter) {
+				t.Errorf("FrontMatter = %#v, expected %#v", doc.FrontMatter, tt.frontMatter)
+			}
+			compareSections(t, doc.Sections, []*Section{{
+				Title:    "Description",
+				Level:    1,
+				Snippets: Snippets{{Type: Text, Content: "Tag the next version"}},
+			}})
+		})
+	}
+
+	if _, err := ParseDocument("+++\nname = [\n+++\n"); err == nil || !strings.HasPrefix(err.Error(), "failed to parse TOML front matter: ") {
+		t.Errorf("expect front matter error, got %v", err)
+	}
+}
+
+func TestParsePositions(t *testing.T) {
+	markdownContent, err := os.ReadFile(filepath.Join("testdata", "positions.md"))
+	if err != nil {
+		t.Fatalf("Failed to read markdown file %s: %v", "positions.md", err)
+	}
+	sections, err := Parse(string(markdownContent))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(sections) != 2 {
+		t.Fatalf("expect 2 sections, got %d", len(sections))
+	}
+	options := sections[0]
+	text, code := options.Snippets[0], options.Snippets[2]
+
+	tests := []struct {
+		name     string
+		got      Position
+		expected string
+	}{
+		{"section start", options.Start, "4:1"},
+		{"section end", options.End, "12:6"},
+		{"text start", text.Start, "5:3"},
+		{"text end", text.End, "5:16"},
+		{"code start", code.Start, "9:3"},
+		{"code end", code.End, "11:4"},
+		{"empty section", sections[1].Start, "14:1"},
+		{"empty section end", sections[1].End, "14:9"},
+		// the { of the object
+		{"offset in code", code.PositionOf(strings.Index(code.Content, "{")), "10:5"},
+		{"offset in text", text.PositionOf(strings.Index(text.Content, "options")), "5:8"},
+	}
+	for _, tt := range tests {
+		if got := tt.got.String(); got != tt.expected {
+			t.Errorf("%s = %s, expected %s", tt.name, got, tt.expected)
+		}
+	}
+}
+
 // loadExpectedSections loads expected sections from a JSON file
 func loadExpectedSections(t *testing.T, filename string) []*Section {
 	t.Helper()
@@ -144,6 +301,10 @@ func compareSections(t *testing.T, actual, expected []*Section) {
 			t.Errorf("Section %d title = %q, expected %q", i, section.Title, expectedSection.Title)
 		}
 
+		if section.Level != expectedSection.Level {
+			t.Errorf("Section %d level = %d, expected %d", i, section.Level, expectedSection.Level)
+		}
+
 		if len(section.Snippets) != len(expectedSection.Snippets) {
 			t.Errorf("Section %d has %d snippets, expected %d", i, len(section.Snippets), len(expectedSection.Snippets))
 			continue
@@ -163,6 +324,115 @@ func compareSections(t *testing.T, actual, expected []*Section) {
 			if snippet.Content != expectedSnippet.Content {
 				t.Errorf("Section %d, snippet %d content = %q, expected %q", i, j, snippet.Content, expectedSnippet.Content)
 			}
+
+			if !reflect.DeepEqual(snippet.Columns, expectedSnippet.Columns) || !reflect.DeepEqual(snippet.Rows, expectedSnippet.Rows) {
+				t.Errorf("Section %d, snippet %d table = %v %v, expected %v %v", i, j, snippet.Columns, snippet.Rows, expectedSnippet.Columns, expectedSnippet.Rows)
+			}
+
+			if !reflect.DeepEqual(snippet.Items, expectedSnippet.Items) {
+				t.Errorf("Section %d, snippet %d items = %q, expected %q", i, j, snippet.Items, expectedSnippet.Items)
+			}
+
+			if !reflect.DeepEqual(snippet.Definitions, expectedSnippet.Definitions) {
+				t.Errorf("Section %d, snippet %d definitions = %v, expected %v", i, j, snippet.Definitions, expectedSnippet.Definitions)
+			}
 		}
 	}
 }
+
+func TestRender_RoundTrip(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			markdownContent, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatalf("Failed to read markdown file %s: %v", file, err)
+			}
+			doc, err := ParseDocument(string(markdownContent))
+			if err != nil {
+				t.Fatalf("ParseDocument() error = %v", err)
+			}
+			rendered := doc.Render()
+			again, err := ParseDocument(rendered)
+			if err != nil {
+				t.Fatalf("ParseDocument() of rendered error = %v\n%s", err, rendered)
+			}
+			if !reflect.DeepEqual(again.FrontMatter, doc.FrontMatter) {
+				t.Errorf("FrontMatter = %#v, expected %#v", again.FrontMatter, doc.FrontMatter)
+			}
+			compareSections(t, again.Sections, doc.Sections)
+			if twice := again.Render(); twice != rendered {
+				t.Errorf("Render() is not stable:\n%s\nthen:\n%s", rendered, twice)
+			}
+		})
+	}
+}
+
+func TestRender_AfterTree(t *testing.T) {
+	content := "# go\n## options\n```json\n[]\n```\n"
+	doc, err := ParseDocument(content)
+	if err != nil {
+		t.Fatalf("ParseDocument() error = %v", err)
+	}
+	expected := doc.Render()
+	tree := doc.Tree()
+	if len(tree) != 1 || len(tree[0].Children) != 1 {
+		t.Fatalf("Tree() = %+v, expected go with one child", tree)
+	}
+	// nesting must not leave children on the flat sections
+	if rendered := doc.Render(); rendered != expected || strings.Count(rendered, "## options") != 1 {
+		t.Errorf("Render() after Tree() =\n%s\nexpected\n%s", rendered, expected)
+	}
+	if len(doc.Sections[0].Children) != 0 {
+		t.Errorf("expect d.Sections to stay flat, got children %+v", doc.Sections[0].Children)
+	}
+}
+
+func TestRender(t *testing.T) {
+	content := `intro text
+#   OPTIONS  
+Options:
+| flags | type |
+|-------|------|
+| ` + "`--push`" + ` | boolean |
+` + "```json" + `
+[]
+` + "```" + `
+## Example
+` + "````sh" + `
+` + "```" + `
+` + "````"
+	doc, err := ParseDocument(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc.Sections[0].Title = "Options"
+	table := doc.Sections[0].Snippets.FindType(Table)
+	table.Content = ""
+	table.Rows = append(table.Rows, map[string]string{"flags": "`-m <a|b>`", "type": "string"})
+
+	expected := `intro text
+# Options
+Options:
+
+| flags       | type    |
+| ----------- | ------- |
+| ` + "`--push`" + `    | boolean |
+| ` + "`-m <a\\|b>`" + ` | string  |
+
+` + "```json" + `
+[]
+` + "```" + `
+
+## Example
+` + "````sh" + `
+` + "```" + `
+` + "````" + `
+`
+	if got := doc.Render(); got != expected {
+		t.Errorf("Render() =\n%s\nexpected:\n%s", got, expected)
+	}
+}
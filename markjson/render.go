@@ -0,0 +1,119 @@
+package markjson
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Render writes sections back as markdown: a header line per section,
+// children after their parent, and snippets separated by blank lines.
+// Text, lists and definition lists are written as their Content, code
+// gets a fence longer than any fence inside it. A table with empty
+// Content is rendered from Columns and Rows with aligned cells.
+// Parsing the result gives back the same sections.
+func (sections Sections) Render() string {
+	var sb strings.Builder
+	renderSections(&sb, sections)
+	return sb.String()
+}
+
+// Render writes the front matter and any text before the first
+// section as they were read, followed by the rendered sections
+func (d *Document) Render() string {
+	return d.preamble + d.Sections.Render()
+}
+
+func renderSections(sb *strings.Builder, sections Sections) {
+	for _, section := range sections {
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		level := section.Level
+		if level < 1 {
+			level = 1
+		}
+		sb.WriteString(strings.Repeat("#", level))
+		if section.Title != "" {
+			sb.WriteString(" " + section.Title)
+		}
+		sb.WriteString("\n")
+		for i, snippet := range section.Snippets {
+			if i > 0 {
+				sb.WriteString("\n")
+			}
+			sb.WriteString(renderSnippet(snippet))
+			sb.WriteString("\n")
+		}
+		renderSections(sb, section.Children)
+	}
+}
+
+func renderSnippet(snippet *Snippet) string {
+	switch {
+	case snippet.Type == Code:
+		marker := strings.Repeat("`", codeFenceLength(snippet.Content))
+		return marker + snippet.Language + "\n" + snippet.Content + "\n" + marker
+	case snippet.Type == Table && snippet.Content == "":
+		return renderTable(snippet.Columns, snippet.Rows)
+	}
+	return snippet.Content
+}
+
+// codeFenceLength returns the length of a backtick fence that
+// no line of content can close
+func codeFenceLength(content string) int {
+	n := 3
+	for _, line := range strings.Split(content, "\n") {
+		if f, _, ok := parseFence(line); ok && f.char == '`' && f.length >= n {
+			n = f.length + 1
+		}
+	}
+	return n
+}
+
+// renderTable writes a GFM table with cells padded
+// to the width of their column
+func renderTable(columns []string, rows []map[string]string) string {
+	escape := func(cell string) string {
+		return strings.ReplaceAll(cell, "|", `\|`)
+	}
+	widths := make([]int, len(columns))
+	for i, column := range columns {
+		widths[i] = utf8.RuneCountInString(escape(column))
+		for _, row := range rows {
+			if w := utf8.RuneCountInString(escape(row[column])); w > widths[i] {
+				widths[i] = w
+			}
+		}
+		if widths[i] < 3 {
+			// the delimiter row needs at least ---
+			widths[i] = 3
+		}
+	}
+	writeRow := func(sb *strings.Builder, cells []string) {
+		sb.WriteString("|")
+		for i, cell := range cells {
+			sb.WriteString(" " + cell + strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)) + " |")
+		}
+	}
+
+	var sb strings.Builder
+	header := make([]string, len(columns))
+	delimiter := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = escape(column)
+		delimiter[i] = strings.Repeat("-", widths[i])
+	}
+	writeRow(&sb, header)
+	sb.WriteString("\n")
+	writeRow(&sb, delimiter)
+	for _, row := range rows {
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			cells[i] = escape(row[column])
+		}
+		sb.WriteString("\n")
+		writeRow(&sb, cells)
+	}
+	return sb.String()
+}
@@ -0,0 +1,554 @@
+package run
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/xhd2015/cli2web/config"
+	"github.com/xhd2015/less-gen/flags"
+)
+
+const docsHelp = `
+Usage: cli2web docs [options] <schema>
+
+Generate reference docs from a schema file or directory.
+
+Options:
+  --format man|markdown|html  output format, default markdown
+  -o,--output <path>          output directory for man and html,
+                              output file for markdown, default stdout
+  --show-hidden               also document hidden commands and options
+`
+
+func handleDocs(args []string) error {
+	var format string
+	var output string
+	var showHidden bool
+	args, err := flags.String("--format", &format).
+		String("-o,--output", &output).
+		Bool("--show-hidden", &showHidden).
+		Help("-h,--help", docsHelp).
+		Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("requires exactly one schema, try `cli2web docs --help`")
+	}
+	s, err := loadSchema(args[0])
+	if err != nil {
+		return err
+	}
+
+	d := &docs{root: s, showHidden: showHidden}
+	switch format {
+	case "", "markdown":
+		content := d.markdown()
+		if output == "" {
+			fmt.Print(content)
+			return nil
+		}
+		return os.WriteFile(output, []byte(content), 0644)
+	case "man":
+		if output == "" {
+			return fmt.Errorf("man requires -o <dir>")
+		}
+		return writeFiles(output, d.manPages())
+	case "html":
+		if output == "" {
+			return fmt.Errorf("html requires -o <dir>")
+		}
+		return writeFiles(output, d.htmlPages())
+	}
+	return fmt.Errorf("unrecognized format: %s, expect man, markdown or html", format)
+}
+
+func writeFiles(dir string, files map[string]string) error {
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// docs renders the commands of a schema as man pages,
+// a markdown reference or a static site
+type docs struct {
+	root       *config.Schema
+	showHidden bool
+}
+
+// visit calls fn with the chain of every visible command, root first
+func (d *docs) visit(fn func(chain []*config.Command)) {
+	var walk func(chain []*config.Command)
+	walk = func(chain []*config.Command) {
+		fn(chain)
+		for _, sub := range d.subcommands(chain[len(chain)-1]) {
+			walk(append(chain[:len(chain):len(chain)], sub))
+		}
+	}
+	walk([]*config.Command{d.root})
+}
+
+func (d *docs) subcommands(cmd *config.Command) []*config.Command {
+	var commands []*config.Command
+	for _, sub := range config.SortedCommands(cmd.Commands) {
+		if sub.Hidden && !d.showHidden {
+			continue
+		}
+		commands = append(commands, sub)
+	}
+	return commands
+}
+
+func (d *docs) options(chain []*config.Command) (options []*config.Option, global []*config.Option) {
+	return visibleOptions(chain[len(chain)-1].Options, d.showHidden), visibleOptions(inheritedOptions(chain), d.showHidden)
+}
+
+// commandNames returns the names along chain, the root
+// is left out if it has no name
+func commandNames(chain []*config.Command) []string {
+	var names []string
+	for i, cmd := range chain {
+		if i == 0 && cmd.Name == "" {
+			continue
+		}
+		names = append(names, cmd.Name)
+	}
+	return names
+}
+
+// commandPath is the URL path of the command, "" for the root
+func commandPath(chain []*config.Command) string {
+	var names []string
+	for _, cmd := range chain[1:] {
+		names = append(names, cmd.Name)
+	}
+	return strings.Join(names, "/")
+}
+
+func synopsis(chain []*config.Command, options []*config.Option, global []*config.Option) string {
+	cmd := chain[len(chain)-1]
+	parts := commandNames(chain)
+	if len(parts) == 0 {
+		parts = []string{"<command>"}
+	}
+	if len(options)+len(global) > 0 {
+		parts = append(parts, "[options]")
+	}
+	if len(cmd.Commands) > 0 && len(cmd.Arguments) == 0 {
+		parts = append(parts, "<command>")
+	}
+	for _, arg := range cmd.Arguments {
+		parts = append(parts, "<"+arg.Name+">")
+	}
+	return strings.Join(parts, " ")
+}
+
+// optionNotes are the default, choices and deprecation of an option
+func optionNotes(opt *config.Option) []string {
+	var notes []string
+	if opt.Default != "" && opt.Type != config.TypeBoolean {
+		notes = append(notes, "default: "+opt.Default)
+	}
+	if len(opt.Choices) > 0 {
+		notes = append(notes, "one of: "+strings.Join(opt.Choices, ", "))
+	}
+	if opt.Experimental {
+		notes = append(notes, "experimental")
+	}
+	if opt.Deprecated != "" {
+		note := "deprecated: " + opt.Deprecated
+		if opt.Replacement != "" {
+			note += ", use " + opt.Replacement + " instead"
+		}
+		notes = append(notes, note)
+	}
+	return notes
+}
+
+func argumentNotes(arg *config.Argument) []string {
+	var notes []string
+	if arg.Default != "" {
+		notes = append(notes, "default: "+arg.Default)
+	}
+	if len(arg.Choices) > 0 {
+		notes = append(notes, "one of: "+strings.Join(arg.Choices, ", "))
+	}
+	return notes
+}
+
+func withNotes(description string, notes []string) string {
+	if len(notes) == 0 {
+		return description
+	}
+	return strings.TrimSpace(description + " (" + strings.Join(notes, "; ") + ")")
+}
+
+func (d *docs) markdown() string {
+	var sb strings.Builder
+	title := d.root.Name
+	if title == "" {
+		title = "Commands"
+	}
+	sb.WriteString("# " + title + "\n\n")
+	if d.root.Description != "" {
+		sb.WriteString(d.root.Description + "\n\n")
+	}
+
+	// table of contents
+	d.visit(func(chain []*config.Command) {
+		if len(chain) == 1 {
+			return
+		}
+		indent := strings.Repeat("  ", len(chain)-2)
+		sb.WriteString(fmt.Sprintf("%s- [%s](#%s)\n", indent, chain[len(chain)-1].Name, markdownAnchor(chain)))
+	})
+
+	sb.WriteString("\n")
+
+	d.visit(func(chain []*config.Command) {
+		if len(chain) == 1 && len(d.root.Options)+len(d.root.Arguments) == 0 {
+			// the root only has the title and TOC
+			return
+		}
+		d.markdownCommand(&sb, chain)
+	})
+	return strings.TrimRight(sb.String(), "\n") + "\n"
+}
+
+func (d *docs) markdownCommand(sb *strings.Builder, chain []*config.Command) {
+	cmd := chain[len(chain)-1]
+	options, global := d.options(chain)
+
+	sb.WriteString("## " + markdownHeading(chain) + "\n\n")
+	if cmd.Experimental {
+		sb.WriteString("> **Experimental**\n\n")
+	}
+	if cmd.Deprecated != "" {
+		msg := "> **Deprecated**: " + cmd.Deprecated
+		if cmd.Replacement != "" {
+			msg += " Use `" + cmd.Replacement + "` instead."
+		}
+		sb.WriteString(msg + "\n\n")
+	}
+	if cmd.Description != "" && len(chain) > 1 {
+		sb.WriteString(cmd.Description + "\n\n")
+	}
+	sb.WriteString("```\n" + synopsis(chain, options, global) + "\n```\n\n")
+	if len(cmd.Aliases) > 0 {
+		sb.WriteString("Aliases: " + strings.Join(cmd.Aliases, ", ") + "\n\n")
+	}
+
+	if len(cmd.Arguments) > 0 {
+		sb.WriteString("### Arguments\n\n| Argument | Description |\n| --- | --- |\n")
+		for _, arg := range cmd.Arguments {
+			sb.WriteString(fmt.Sprintf("| `%s` | %s |\n", arg.Name, markdownCell(withNotes(arg.Description, argumentNotes(arg)))))
+		}
+		sb.WriteString("\n")
+	}
+	writeOptionsTable := func(title string, options []*config.Option) {
+		if len(options) == 0 {
+			return
+		}
+		sb.WriteString("### " + title + "\n\n| Option | Description |\n| --- | --- |\n")
+		for _, opt := range options {
+			sb.WriteString(fmt.Sprintf("| `%s` | %s |\n", opt.Spec().Display(), markdownCell(withNotes(opt.Description, optionNotes(opt)))))
+		}
+		sb.WriteString("\n")
+	}
+	writeOptionsTable("Options", options)
+	writeOptionsTable("Global options", global)
+
+	if subs := d.subcommands(cmd); len(subs) > 0 {
+		sb.WriteString("### Commands\n\n")
+		for _, sub := range subs {
+			subChain := append(chain[:len(chain):len(chain)], sub)
+			sb.WriteString(fmt.Sprintf("- [%s](#%s): %s\n", sub.Name, markdownAnchor(subChain), sub.Description))
+		}
+		sb.WriteString("\n")
+	}
+	if len(cmd.Examples) > 0 {
+		sb.WriteString("### Examples\n\n")
+		for _, ex := range cmd.Examples {
+			if ex.Description != "" {
+				sb.WriteString(ex.Description + "\n\n")
+			}
+			if ex.Usage != "" {
+				sb.WriteString("```sh\n" + ex.Usage + "\n```\n\n")
+			}
+		}
+	}
+	if len(chain) > 2 {
+		parent := chain[:len(chain)-1]
+		sb.WriteString(fmt.Sprintf("See also: [%s](#%s)\n\n", markdownHeading(parent), markdownAnchor(parent)))
+	}
+}
+
+func markdownHeading(chain []*config.Command) string {
+	return strings.Join(commandNames(chain), " ")
+}
+
+var anchorRemoveRegex = regexp.MustCompile(`[^a-z0-9 _-]`)
+
+// markdownAnchor is the anchor GitHub generates for the heading of chain
+func markdownAnchor(chain []*config.Command) string {
+	heading := strings.ToLower(markdownHeading(chain))
+	return strings.ReplaceAll(anchorRemoveRegex.ReplaceAllString(heading, ""), " ", "-")
+}
+
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+// manPages returns one man(7) page per command, named
+// like git-tag-next.1 after the command path
+func (d *docs) manPages() map[string]string {
+	pages := make(map[string]string)
+	d.visit(func(chain []*config.Command) {
+		pages[manName(chain)+".1"] = d.manPage(chain)
+	})
+	return pages
+}
+
+func manName(chain []*config.Command) string {
+	names := commandNames(chain)
+	if len(names) == 0 {
+		return "index"
+	}
+	return strings.Join(names, "-")
+}
+
+func (d *docs) manPage(chain []*config.Command) string {
+	cmd := chain[len(chain)-1]
+	options, global := d.options(chain)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(".TH %s 1\n", roffEscape(strings.ToUpper(manName(chain)))))
+	sb.WriteString(".SH NAME\n")
+	name := roffEscape(manName(chain))
+	if cmd.Description != "" {
+		name += ` \- ` + roffEscape(firstLine(cmd.Description))
+	}
+	sb.WriteString(name + "\n")
+	sb.WriteString(".SH SYNOPSIS\n.B " + roffEscape(synopsis(chain, options, global)) + "\n")
+	if cmd.Description != "" || cmd.Deprecated != "" || cmd.Experimental {
+		sb.WriteString(".SH DESCRIPTION\n")
+		if cmd.Deprecated != "" {
+			msg := "Deprecated: " + cmd.Deprecated
+			if cmd.Replacement != "" {
+				msg += " Use " + cmd.Replacement + " instead."
+			}
+			sb.WriteString(roffText(msg) + "\n.PP\n")
+		}
+		if cmd.Experimental {
+			sb.WriteString("This command is experimental.\n.PP\n")
+		}
+		sb.WriteString(roffText(cmd.Description) + "\n")
+	}
+	if len(cmd.Arguments) > 0 {
+		sb.WriteString(".SH ARGUMENTS\n")
+		for _, arg := range cmd.Arguments {
+			sb.WriteString(".TP\n\\fI" + roffEscape(arg.Name) + "\\fR\n" + roffText(withNotes(arg.Description, argumentNotes(arg))) + "\n")
+		}
+	}
+	writeOptions := func(title string, options []*config.Option) {
+		if len(options) == 0 {
+			return
+		}
+		sb.WriteString(".SH " + title + "\n")
+		for _, opt := range options {
+			sb.WriteString(".TP\n" + manFlags(opt.Spec()) + "\n" + roffText(withNotes(opt.Description, optionNotes(opt))) + "\n")
+		}
+	}
+	writeOptions("OPTIONS", options)
+	writeOptions("GLOBAL OPTIONS", global)
+
+	subs := d.subcommands(cmd)
+	if len(subs) > 0 {
+		sb.WriteString(".SH COMMANDS\n")
+		for _, sub := range subs {
+			sb.WriteString(".TP\n\\fB" + roffEscape(sub.Name) + "\\fR\n" + roffText(sub.Description) + "\n")
+		}
+	}
+	if len(cmd.Examples) > 0 {
+		sb.WriteString(".SH EXAMPLES\n")
+		for _, ex := range cmd.Examples {
+			if ex.Description != "" {
+				sb.WriteString(roffText(ex.Description) + "\n")
+			}
+			if ex.Usage != "" {
+				sb.WriteString(".PP\n.nf\n.RS\n" + roffLines(ex.Usage) + "\n.RE\n.fi\n")
+			}
+			sb.WriteString(".PP\n")
+		}
+	}
+
+	var seeAlso []string
+	if len(chain) > 1 {
+		seeAlso = append(seeAlso, manName(chain[:len(chain)-1]))
+	}
+	for _, sub := range subs {
+		seeAlso = append(seeAlso, manName(append(chain[:len(chain):len(chain)], sub)))
+	}
+	if len(seeAlso) > 0 {
+		refs := make([]string, len(seeAlso))
+		for i, ref := range seeAlso {
+			refs[i] = `\fB` + roffEscape(ref) + `\fR(1)`
+		}
+		sb.WriteString(".SH SEE ALSO\n" + strings.Join(refs, ", ") + "\n")
+	}
+	return sb.String()
+}
+
+func manFlags(spec *config.FlagSpec) string {
+	var names []string
+	for _, name := range spec.Names() {
+		names = append(names, `\fB`+roffEscape(name)+`\fR`)
+	}
+	line := strings.Join(names, ", ")
+	if spec.Placeholder != "" {
+		line += ` \fI` + roffEscape(spec.Placeholder) + `\fR`
+	}
+	return line
+}
+
+// roffEscape escapes backslashes and dashes for use inside a line
+func roffEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\e`)
+	return strings.ReplaceAll(s, "-", `\-`)
+}
+
+// roffText escapes a paragraph, lines starting with
+// a control character are protected
+func roffText(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	for i, line := range lines {
+		line = roffEscape(strings.TrimSpace(line))
+		if line == "" {
+			line = ".PP"
+		} else if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			line = `\&` + line
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// roffLines escapes preformatted lines
+func roffLines(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		line = roffEscape(line)
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			line = `\&` + line
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+func firstLine(s string) string {
+	if idx := strings.Index(s, "\n"); idx >= 0 {
+		return strings.TrimSpace(s[:idx])
+	}
+	return s
+}
+
+// htmlPages returns a static site with one page per command,
+// index.html for the root and commands/git/tag-next.html for a
+// subcommand, so that no command name takes the root's page
+func (d *docs) htmlPages() map[string]string {
+	pages := make(map[string]string)
+	d.visit(func(chain []*config.Command) {
+		file := htmlFile(commandPath(chain))
+		// relative links from the page's directory
+		base := strings.Repeat("../", strings.Count(file, "/"))
+		link := func(p string) string {
+			return base + htmlFile(strings.TrimPrefix(p, "/"))
+		}
+		title := strings.Join(commandNames(chain), " ")
+		pages[file] = `<!DOCTYPE html><html><head><meta charset="utf-8"><title>` + html.EscapeString(title) + `</title>` +
+			`<style>` + styleCSS + `</style>` +
+			`</head><body><div class="container">` +
+			renderSidebarLinks(d.root, d.showHidden, link) +
+			`<div class="main-content">` + d.htmlCommand(chain, link) + `</div></div>` +
+			`<script>` + scriptJS + `</script>` +
+			`</body></html>`
+	})
+	return pages
+}
+
+func htmlFile(cmdPath string) string {
+	if cmdPath == "" {
+		return "index.html"
+	}
+	return path.Join("commands", path.Clean(cmdPath)) + ".html"
+}
+
+func (d *docs) htmlCommand(chain []*config.Command, link func(path string) string) string {
+	cmd := chain[len(chain)-1]
+	options, global := d.options(chain)
+
+	var sb strings.Builder
+	title := strings.Join(commandNames(chain), " ")
+	sb.WriteString(fmt.Sprintf(`<h1>%s%s</h1>`, html.EscapeString(title), renderBadges(&cmd.Lifecycle)))
+	sb.WriteString(renderDeprecation("This command", &cmd.Lifecycle))
+	if cmd.Description != "" {
+		sb.WriteString(fmt.Sprintf(`<p>%s</p>`, html.EscapeString(cmd.Description)))
+	}
+	sb.WriteString(fmt.Sprintf(`<pre><code>%s</code></pre>`, html.EscapeString(synopsis(chain, options, global))))
+
+	if len(cmd.Arguments) > 0 {
+		sb.WriteString(`<h2>Arguments</h2><dl>`)
+		for _, arg := range cmd.Arguments {
+			sb.WriteString(fmt.Sprintf(`<dt><code>%s</code></dt><dd>%s</dd>`,
+				html.EscapeString(arg.Name), html.EscapeString(withNotes(arg.Description, argumentNotes(arg)))))
+		}
+		sb.WriteString(`</dl>`)
+	}
+	writeOptions := func(title string, options []*config.Option) {
+		if len(options) == 0 {
+			return
+		}
+		sb.WriteString(`<h2>` + title + `</h2><dl>`)
+		for _, opt := range options {
+			sb.WriteString(fmt.Sprintf(`<dt><code>%s</code>%s</dt><dd>%s</dd>`,
+				html.EscapeString(opt.Spec().Display()), renderBadges(&opt.Lifecycle),
+				html.EscapeString(withNotes(opt.Description, optionNotes(opt)))))
+		}
+		sb.WriteString(`</dl>`)
+	}
+	writeOptions("Options", options)
+	writeOptions("Global options", global)
+
+	if subs := d.subcommands(cmd); len(subs) > 0 {
+		sb.WriteString(`<h2>Commands</h2><ul>`)
+		for _, sub := range subs {
+			subPath := commandPath(append(chain[:len(chain):len(chain)], sub))
+			sb.WriteString(fmt.Sprintf(`<li><a href="%s">%s</a>: %s</li>`,
+				html.EscapeString(link("/"+subPath)), html.EscapeString(sub.Name), html.EscapeString(sub.Description)))
+		}
+		sb.WriteString(`</ul>`)
+	}
+	if len(cmd.Examples) > 0 {
+		sb.WriteString(`<h2>Examples</h2><ul>`)
+		for _, ex := range cmd.Examples {
+			sb.WriteString(fmt.Sprintf(`<li><div>%s</div><pre><code>%s</code></pre></li>`,
+				html.EscapeString(ex.Description), html.EscapeString(ex.Usage)))
+		}
+		sb.WriteString(`</ul>`)
+	}
+	return sb.String()
+}
@@ -0,0 +1,120 @@
+package run
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/cli2web/config"
+)
+
+func docsSchema() *config.Schema {
+	return &config.Schema{
+		Name:    "kool",
+		Options: []*config.Option{{Flags: "-c, --config <file>", Description: "config file", Persistent: true}},
+		Commands: []*config.Command{
+			{
+				Name:        "git",
+				Description: "Git commands",
+				Commands: []*config.Command{
+					{
+						Name:        "tag-next",
+						Description: "Get the next git tag",
+						Arguments:   []*config.Argument{{Name: "dir", Default: "."}},
+						Options: []*config.Option{
+							{Flags: "--format <fmt>", Description: "output | format", Choices: []string{"text", "json"}},
+							{Flags: "--token <t>", Lifecycle: config.Lifecycle{Hidden: true}},
+						},
+						Examples: []*config.Example{{Usage: ".tag-next --push", Description: "Push"}},
+					},
+				},
+			},
+			{Name: "secret", Lifecycle: config.Lifecycle{Hidden: true}},
+		},
+	}
+}
+
+func TestDocsMarkdown(t *testing.T) {
+	md := (&docs{root: docsSchema()}).markdown()
+	for _, expected := range []string{
+		"- [git](#kool-git)\n  - [tag-next](#kool-git-tag-next)\n",
+		"## kool git tag-next\n",
+		"```\nkool git tag-next [options] <dir>\n```",
+		"| `dir` | (default: .) |",
+		"| `--format <fmt>` | output \\| format (one of: text, json) |",
+		"### Global options\n\n| Option | Description |\n| --- | --- |\n| `-c, --config <file>` | config file |",
+		"- [tag-next](#kool-git-tag-next): Get the next git tag",
+		"```sh\n.tag-next --push\n```",
+		"See also: [kool git](#kool-git)",
+	} {
+		if !strings.Contains(md, expected) {
+			t.Errorf("Expected markdown to contain %q, got:\n%s", expected, md)
+		}
+	}
+	for _, hidden := range []string{"secret", "--token"} {
+		if strings.Contains(md, hidden) {
+			t.Errorf("Expected hidden %s to be left out", hidden)
+		}
+	}
+}
+
+func TestDocsManPages(t *testing.T) {
+	pages := (&docs{root: docsSchema()}).manPages()
+	var names []string
+	for name := range pages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if strings.Join(names, " ") != "kool-git-tag-next.1 kool-git.1 kool.1" {
+		t.Errorf("Unexpected pages %v", names)
+	}
+	page := pages["kool-git-tag-next.1"]
+	for _, expected := range []string{
+		".TH KOOL\\-GIT\\-TAG\\-NEXT 1\n",
+		".SH NAME\nkool\\-git\\-tag\\-next \\- Get the next git tag\n",
+		".TP\n\\fB\\-\\-format\\fR \\fIfmt\\fR\n",
+		".SH GLOBAL OPTIONS\n",
+		// a leading dot would be a roff request
+		"\\&.tag\\-next \\-\\-push\n",
+		".SH SEE ALSO\n\\fBkool\\-git\\fR(1)\n",
+	} {
+		if !strings.Contains(page, expected) {
+			t.Errorf("Expected man page to contain %q, got:\n%s", expected, page)
+		}
+	}
+}
+
+func TestDocsHTMLPages(t *testing.T) {
+	pages := (&docs{root: docsSchema()}).htmlPages()
+	if len(pages) != 3 {
+		t.Fatalf("Expected 3 pages, got %d", len(pages))
+	}
+	page := pages["commands/git/tag-next.html"]
+	if !strings.Contains(page, `<div class="sidebar">`) || !strings.Contains(page, `<a href="../../commands/git/tag-next.html">`) {
+		t.Errorf("Expected the sidebar with relative links, got:\n%s", page)
+	}
+	if !strings.Contains(pages["commands/git.html"], `<a href="../commands/git/tag-next.html">tag-next</a>`) {
+		t.Errorf("Expected git.html to link tag-next, got:\n%s", pages["commands/git.html"])
+	}
+	if !strings.Contains(pages["index.html"], `<a href="commands/git.html">`) {
+		t.Errorf("Expected index.html to link git, got:\n%s", pages["index.html"])
+	}
+}
+
+func TestDocsHTMLPages_IndexCommand(t *testing.T) {
+	s := &config.Schema{
+		Name:        "kool",
+		Description: "Kool utilities",
+		Commands:    []*config.Command{{Name: "index", Description: "Build the search index"}},
+	}
+	pages := (&docs{root: s}).htmlPages()
+	if len(pages) != 2 {
+		t.Fatalf("Expected 2 pages, got %d", len(pages))
+	}
+	if !strings.Contains(pages["index.html"], "Kool utilities") {
+		t.Errorf("Expected index.html to be the root page, got:\n%s", pages["index.html"])
+	}
+	if !strings.Contains(pages["commands/index.html"], "Build the search index") {
+		t.Errorf("Expected the index command page, got:\n%s", pages["commands/index.html"])
+	}
+}
@@ -0,0 +1,195 @@
+package run
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/xhd2015/cli2web/config"
+)
+
+const secretMask = "******"
+
+// invocation is a command ready to be executed, built from
+// the submitted form
+type invocation struct {
+	Args []string
+	// Env holds extra KEY=VALUE pairs appended to the current environment
+	Env []string
+	// Stdin lines written to the command, one per value
+	Stdin []string
+
+	secrets []string
+}
+
+// buildInvocation converts the submitted form into argv, chain is the
+// command path starting with the root. Options are passed by their
+// canonical flag name.
+func buildInvocation(chain []*config.Command, formData map[string]string) *invocation {
+	inv := &invocation{}
+	cmd := chain[len(chain)-1]
+	inherited := config.InheritedOptions(chain)
+
+	for depth, c := range chain {
+		if c.Name != "" {
+			inv.Args = append(inv.Args, c.Name)
+		}
+		for _, opt := range inherited {
+			if opt.Depth == depth && opt.Placement == config.PlacementBefore {
+				inv.addOption(opt.Option, formData)
+			}
+		}
+	}
+
+	// Add arguments
+	for _, arg := range cmd.Arguments {
+		value := formValue(formData, "arg-"+arg.Name, arg.Secret, arg.Default)
+		if value == "" {
+			continue
+		}
+		if arg.Secret {
+			inv.secrets = append(inv.secrets, value)
+		}
+		if !inv.redirect(arg.Env, arg.Stdin, value) {
+			inv.Args = append(inv.Args, value)
+		}
+	}
+
+	// Add options
+	for _, opt := range cmd.Options {
+		inv.addOption(opt, formData)
+	}
+	for _, opt := range inherited {
+		if opt.Placement != config.PlacementBefore {
+			inv.addOption(opt.Option, formData)
+		}
+	}
+	return inv
+}
+
+// validateForm checks the submitted values against the types and
+// choices declared in the schema, empty values are left out.
+// Secret values are masked in the error.
+func validateForm(chain []*config.Command, formData map[string]string) error {
+	cmd := chain[len(chain)-1]
+	for _, arg := range cmd.Arguments {
+		if err := validateValue(arg.Type, arg.Choices, arg.Secret, formData["arg-"+arg.Name]); err != nil {
+			return fmt.Errorf("argument %s: %v", arg.Name, err)
+		}
+	}
+	options := append(cmd.Options[:len(cmd.Options):len(cmd.Options)], inheritedOptions(chain)...)
+	for _, opt := range options {
+		spec := opt.Spec()
+		if spec.Name == "" || opt.Type == config.TypeBoolean {
+			continue
+		}
+		if err := validateValue(opt.Type, opt.Choices, opt.Secret, formData[spec.ID()]); err != nil {
+			return fmt.Errorf("option %s: %v", spec.Name, err)
+		}
+	}
+	return nil
+}
+
+func validateValue(typ string, choices []string, secret bool, value string) error {
+	if value == "" {
+		return nil
+	}
+	shown := strconv.Quote(value)
+	if secret {
+		shown = secretMask
+	}
+	if typ == config.TypeNumber {
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("expect a number, got %s", shown)
+		}
+	}
+	if len(choices) > 0 {
+		for _, choice := range choices {
+			if choice == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s, got %s", strings.Join(choices, ", "), shown)
+	}
+	return nil
+}
+
+func (inv *invocation) addOption(opt *config.Option, formData map[string]string) {
+	spec := opt.Spec()
+	if spec.Name == "" {
+		return
+	}
+	id := spec.ID()
+	if opt.Type == config.TypeBoolean {
+		if formData[id] == "on" && !inv.redirect(opt.Env, opt.Stdin, "true") {
+			inv.Args = append(inv.Args, spec.Name)
+		}
+	} else if value := formValue(formData, id, opt.Secret, opt.Default); value != "" && (opt.Type != "" || spec.Placeholder != "") {
+		if opt.Secret {
+			inv.secrets = append(inv.secrets, value)
+		}
+		if !inv.redirect(opt.Env, opt.Stdin, value) {
+			inv.Args = append(inv.Args, spec.Name, value)
+		}
+	}
+}
+
+// formValue returns the submitted value of the field id. The
+// default of a secret is not sent to the browser, so it applies
+// here when the field is left empty.
+func formValue(formData map[string]string, id string, secret bool, def string) string {
+	if value := formData[id]; value != "" || !secret {
+		return value
+	}
+	return def
+}
+
+// redirect routes the value through env or stdin if configured,
+// returns false if the value should go to argv
+func (inv *invocation) redirect(env string, stdin bool, value string) bool {
+	if env != "" {
+		inv.Env = append(inv.Env, env+"="+value)
+		return true
+	}
+	if stdin {
+		inv.Stdin = append(inv.Stdin, value)
+		return true
+	}
+	return false
+}
+
+// Command creates the exec.Cmd, stdout and stderr are left
+// for the caller to attach
+func (inv *invocation) Command() *exec.Cmd {
+	cmd := exec.Command(inv.Args[0], inv.Args[1:]...)
+	if len(inv.Env) > 0 {
+		cmd.Env = append(os.Environ(), inv.Env...)
+	}
+	if len(inv.Stdin) > 0 {
+		cmd.Stdin = strings.NewReader(strings.Join(inv.Stdin, "\n") + "\n")
+	}
+	return cmd
+}
+
+// Mask replaces every secret value in s
+func (inv *invocation) Mask(s string) string {
+	for _, secret := range inv.secrets {
+		s = strings.ReplaceAll(s, secret, secretMask)
+	}
+	return s
+}
+
+// String renders the command line with secrets masked,
+// e.g. TOKEN=****** kool login --user bob
+func (inv *invocation) String() string {
+	var parts []string
+	parts = append(parts, inv.Env...)
+	parts = append(parts, inv.Args...)
+	line := inv.Mask(strings.Join(parts, " "))
+	if len(inv.Stdin) > 0 {
+		line += " < " + inv.Mask(strings.Join(inv.Stdin, ", "))
+	}
+	return line
+}
@@ -0,0 +1,180 @@
+package run
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/cli2web/config"
+)
+
+func TestBuildInvocation_Secrets(t *testing.T) {
+	schema := &config.Schema{Name: "kool"}
+	cmd := &config.Command{
+		Name: "login",
+		Arguments: []*config.Argument{
+			{Name: "user", Type: "string"},
+		},
+		Options: []*config.Option{
+			{Flags: "-t, --token <token>", Type: "string", Secret: true},
+			{Flags: "--api-key", Type: "string", Secret: true, Env: "API_KEY"},
+			{Flags: "--password", Type: "string", Secret: true, Stdin: true},
+		},
+	}
+	inv := buildInvocation([]*config.Command{schema, cmd}, map[string]string{
+		"arg-user":     "bob",
+		"opt-token":    "tok123",
+		"opt-api-key":  "key456",
+		"opt-password": "pass789",
+	})
+
+	expectArgs := []string{"kool", "login", "bob", "--token", "tok123"}
+	if !reflect.DeepEqual(inv.Args, expectArgs) {
+		t.Errorf("Args = %v, expected %v", inv.Args, expectArgs)
+	}
+	if !reflect.DeepEqual(inv.Env, []string{"API_KEY=key456"}) {
+		t.Errorf("Env = %v, expected [API_KEY=key456]", inv.Env)
+	}
+	if !reflect.DeepEqual(inv.Stdin, []string{"pass789"}) {
+		t.Errorf("Stdin = %v, expected [pass789]", inv.Stdin)
+	}
+
+	display := inv.String()
+	expectDisplay := "API_KEY=****** kool login bob --token ****** < ******"
+	if display != expectDisplay {
+		t.Errorf("String() = %q, expected %q", display, expectDisplay)
+	}
+	for _, secret := range []string{"tok123", "key456", "pass789"} {
+		if strings.Contains(display, secret) {
+			t.Errorf("String() leaks secret %q: %s", secret, display)
+		}
+	}
+}
+
+func TestBuildInvocation_SecretDefaults(t *testing.T) {
+	schema := &config.Schema{Name: "kool"}
+	cmd := &config.Command{
+		Name: "login",
+		Arguments: []*config.Argument{
+			{Name: "pin", Secret: true, Default: "4242"},
+		},
+		Options: []*config.Option{
+			{Flags: "--token <token>", Type: "string", Secret: true, Default: "tok123"},
+			{Flags: "--user <name>", Type: "string", Default: "bob"},
+		},
+	}
+	// the form has no value for secrets with a default
+	inv := buildInvocation([]*config.Command{schema, cmd}, map[string]string{"opt-token": ""})
+	expectArgs := []string{"kool", "login", "4242", "--token", "tok123"}
+	if !reflect.DeepEqual(inv.Args, expectArgs) {
+		t.Errorf("Args = %v, expected %v", inv.Args, expectArgs)
+	}
+	if display := inv.String(); display != "kool login ****** --token ******" {
+		t.Errorf("String() = %q, expected the defaults masked", display)
+	}
+
+	inv = buildInvocation([]*config.Command{schema, cmd}, map[string]string{"arg-pin": "1111", "opt-token": "typed"})
+	expectArgs = []string{"kool", "login", "1111", "--token", "typed"}
+	if !reflect.DeepEqual(inv.Args, expectArgs) {
+		t.Errorf("Args = %v, expected %v", inv.Args, expectArgs)
+	}
+}
+
+func TestBuildInvocation_PersistentPlacement(t *testing.T) {
+	tagNext := &config.Command{
+		Name: "tag-next",
+		Options: []*config.Option{
+			{Flags: "--push", Type: "boolean"},
+			// shadows the root's --config
+			{Flags: "-c, --config <file>", Type: "string"},
+		},
+	}
+	git := &config.Command{
+		Name:     "git",
+		Commands: []*config.Command{tagNext},
+		Options: []*config.Option{
+			{Flags: "--dir <dir>", Type: "string", Persistent: true},
+			{Flags: "--local", Type: "boolean"},
+		},
+	}
+	formData := map[string]string{
+		"opt-verbose": "on",
+		"opt-config":  "leaf.json",
+		"opt-dir":     "/tmp",
+		"opt-local":   "on",
+		"opt-push":    "on",
+	}
+
+	tests := []struct {
+		name      string
+		placement string
+		expected  string
+	}{
+		{name: "default", expected: "kool git tag-next --push --config leaf.json --verbose --dir /tmp"},
+		{name: "before", placement: config.PlacementBefore, expected: "kool --verbose git --dir /tmp tag-next --push --config leaf.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := &config.Schema{
+				Name:                "kool",
+				Commands:            []*config.Command{git},
+				PersistentPlacement: tt.placement,
+				Options: []*config.Option{
+					{Flags: "-v, --verbose", Type: "boolean", Persistent: true},
+					{Flags: "--config <file>", Type: "string", Persistent: true},
+				},
+			}
+			inv := buildInvocation([]*config.Command{root, git, tagNext}, formData)
+			if got := strings.Join(inv.Args, " "); got != tt.expected {
+				t.Errorf("Args = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestValidateForm(t *testing.T) {
+	root := &config.Schema{
+		Name: "kool",
+		Options: []*config.Option{
+			{Flags: "--jobs <n>", Type: "number", Persistent: true},
+		},
+	}
+	cmd := &config.Command{
+		Name: "bump",
+		Arguments: []*config.Argument{
+			{Name: "kind", Choices: []string{"major", "minor"}},
+			{Name: "pin", Type: "number", Secret: true},
+		},
+		Options: []*config.Option{
+			{Flags: "--format <fmt>", Choices: []string{"json", "text"}},
+			{Flags: "--dry-run", Type: "boolean"},
+			{Flags: "--region <name>", Choices: []string{"eu", "us"}, Secret: true},
+		},
+	}
+	tests := []struct {
+		formData map[string]string
+		expected string
+	}{
+		{formData: map[string]string{}},
+		{formData: map[string]string{"arg-kind": "minor", "opt-format": "json", "opt-jobs": "2.5", "opt-dry-run": "on"}},
+		{formData: map[string]string{"arg-kind": "patch"}, expected: `argument kind: must be one of major, minor, got "patch"`},
+		{formData: map[string]string{"opt-format": "yaml"}, expected: `option --format: must be one of json, text, got "yaml"`},
+		{formData: map[string]string{"opt-jobs": "many"}, expected: `option --jobs: expect a number, got "many"`},
+		{formData: map[string]string{"arg-pin": "hunter2"}, expected: `argument pin: expect a number, got ******`},
+		{formData: map[string]string{"opt-region": "hunter2"}, expected: `option --region: must be one of eu, us, got ******`},
+	}
+	for _, tt := range tests {
+		err := validateForm([]*config.Command{root, cmd}, tt.formData)
+		var got string
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.expected {
+			t.Errorf("validateForm(%v) = %q, expected %q", tt.formData, got, tt.expected)
+		}
+		// secrets never show up in errors, which are logged and sent back
+		if strings.Contains(got, "hunter2") {
+			t.Errorf("validateForm(%v) = %q, leaks the secret value", tt.formData, got)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html"
 	"io"
@@ -12,14 +13,18 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/xhd2015/cli2web/completion"
 	"github.com/xhd2015/cli2web/config"
+	"github.com/xhd2015/cli2web/importhelp"
 	"github.com/xhd2015/cli2web/schema"
 	"github.com/xhd2015/less-gen/flags"
 	"github.com/xhd2015/less-gen/netport"
@@ -32,12 +37,26 @@ cli2web converts your CLI into web interface
 Usage: cli2web --schema schema.json
 
 Options:
-  --schema <file>            path to the schema file
+  --schema <file>            path to the schema file, json, yaml or toml, or a
+                             schema directory, repeat to serve several CLIs
+  --schemas <dir>            serve each schema file and directory in <dir>
   --port <port>              port to serve the web interface on
+  --show-hidden              also show hidden commands and options
 
 Other commands:
-  cli2web parse-schema <schema.json>    parse schema from json file
-  cli2web parse-schema <dir>            parse schema from directory
+  cli2web tui --schema schema.json      browse and run commands in the terminal
+  cli2web parse-schema <schema.json>    validate schema from json, yaml or toml file
+  cli2web parse-schema <dir>            validate and print schema from directory
+  cli2web import-help [--depth N] [--format json|dir] [-o <out>] -- <cmd>...
+                                        generate schema by parsing <cmd> --help
+  cli2web export-dir <schema.json> <outdir>
+                                        write schema as a markdown directory
+  cli2web fmt-schema [--check] <dir>    format the markdown files of a schema directory
+  cli2web docs [--format man|markdown|html] [-o <out>] <schema>
+                                        generate reference docs
+  cli2web jsonschema                    print the JSON Schema of schema files
+  cli2web completion bash|zsh|fish|powershell <schema>
+                                        generate shell completion script
 
 The schema:
   cli2web example
@@ -54,13 +73,24 @@ func Main(args []string) error {
 }
 
 type RunOptions struct {
-	Schema       []byte
+	Schema []byte
+	// SchemaFormat is the format of Schema: json, yaml or toml,
+	// detected from the content if empty
+	SchemaFormat string
+	// SchemaPath is the file Schema was read from, $ref and include
+	// are read relative to it, or to the working directory if empty
+	SchemaPath   string
 	SchemaConfig *config.Schema
-	Port         int
+	// Schemas are served along with Schema or SchemaConfig if
+	// set, several schemas are each served under /<name>
+	Schemas []*config.Schema
+	Port    int
+	// ShowHidden also lists hidden commands and options
+	ShowHidden bool
 }
 
 func Run(opts RunOptions) error {
-	return runConfig(opts.Schema, opts.SchemaConfig, opts.Port)
+	return runConfig(opts)
 }
 
 var upgrader = websocket.Upgrader{
@@ -68,33 +98,101 @@ var upgrader = websocket.Upgrader{
 	WriteBufferSize: 1024,
 }
 
-func renderSidebar(cfg *config.Schema) string {
+func renderSidebar(cfg *config.Schema, showHidden bool) string {
+	return renderSidebarLinks(cfg, showHidden, func(path string) string {
+		return path
+	})
+}
+
+// renderSidebarLinks renders the sidebar, link converts a command
+// path like "/git/tag-next" to the href of its page
+func renderSidebarLinks(cfg *config.Schema, showHidden bool, link func(path string) string) string {
 	var sb strings.Builder
+	sb.WriteString(`<div class="sidebar">`)
+	renderSidebarSection(&sb, cfg, showHidden, "", "", link)
+	sb.WriteString(`</div>`)
+	return sb.String()
+}
+
+// renderSidebarSection renders the header and command tree of cfg,
+// command paths start with prefix. The header links to home if set.
+func renderSidebarSection(sb *strings.Builder, cfg *config.Schema, showHidden bool, prefix string, home string, link func(path string) string) {
 	header := "Commands"
 	if cfg.Name != "" {
 		header = cfg.Name + " Commands"
 	}
-	sb.WriteString(`<div class="sidebar"><h2>` + html.EscapeString(header) + `</h2><ul class="tree">`)
+	header = html.EscapeString(header)
+	if home != "" {
+		header = `<a href="` + html.EscapeString(home) + `">` + header + `</a>`
+	}
+	sb.WriteString(`<h2>` + header + `</h2><ul class="tree">`)
 	var renderCommands func([]*config.Command, string)
 	renderCommands = func(commands []*config.Command, prefix string) {
-		for _, cmd := range commands {
+		for _, cmd := range config.SortedCommands(commands) {
+			if cmd.Hidden && !showHidden {
+				continue
+			}
 			path := prefix + "/" + cmd.Name
+			name := html.EscapeString(cmd.Name)
+			if cmd.Deprecated != "" {
+				name = fmt.Sprintf(`<s class="deprecated" title="%s">%s</s>`, html.EscapeString(cmd.Deprecated), name)
+			}
+			name += renderBadges(&cmd.Lifecycle)
 			sb.WriteString("<li>")
 			if len(cmd.Commands) > 0 {
-				sb.WriteString(fmt.Sprintf(`<span class="caret" data-path="%s">`+html.EscapeString(cmd.Name)+`</span>`, html.EscapeString(path)))
+				sb.WriteString(fmt.Sprintf(`<span class="caret" data-path="%s">`+name+`</span>`, html.EscapeString(path)))
 				sb.WriteString(`<ul class="nested">`)
 				renderCommands(cmd.Commands, path)
 				sb.WriteString(`</ul>`)
 			} else {
 				sb.WriteString(fmt.Sprintf(`<a href="%s">%s</a>: %s`,
-					html.EscapeString(path), html.EscapeString(cmd.Name), html.EscapeString(cmd.Description)))
+					html.EscapeString(link(path)), name, html.EscapeString(cmd.Description)))
 			}
 			sb.WriteString("</li>")
 		}
 	}
-	renderCommands(cfg.Commands, "")
-	sb.WriteString(`</ul></div>`)
-	return sb.String()
+	renderCommands(cfg.Commands, prefix)
+	sb.WriteString(`</ul>`)
+}
+
+// renderBadges renders the experimental and hidden markers
+func renderBadges(lifecycle *config.Lifecycle) string {
+	var badges string
+	if lifecycle.Experimental {
+		badges += ` <span class="badge experimental">experimental</span>`
+	}
+	if lifecycle.Hidden {
+		badges += ` <span class="badge hidden">hidden</span>`
+	}
+	return badges
+}
+
+// renderDeprecation renders a warning banner, empty if not deprecated
+func renderDeprecation(subject string, lifecycle *config.Lifecycle) string {
+	if lifecycle.Deprecated == "" {
+		return ""
+	}
+	msg := html.EscapeString(subject) + " is deprecated: " + html.EscapeString(lifecycle.Deprecated)
+	if lifecycle.Replacement != "" {
+		msg += ` Use <code>` + html.EscapeString(lifecycle.Replacement) + `</code> instead.`
+	}
+	return `<div class="banner deprecated">` + msg + `</div>`
+}
+
+// findCommandChain finds the command at pathParts, returning
+// all commands along the path starting with root
+func findCommandChain(root *config.Command, pathParts []string) ([]*config.Command, bool) {
+	chain := []*config.Command{root}
+	commands := root.Commands
+	for _, part := range pathParts {
+		cmd, ok := findCommand(commands, []string{part})
+		if !ok {
+			return nil, false
+		}
+		chain = append(chain, cmd)
+		commands = cmd.Commands
+	}
+	return chain, len(pathParts) > 0
 }
 
 func findCommand(commands []*config.Command, pathParts []string) (*config.Command, bool) {
@@ -102,7 +200,7 @@ func findCommand(commands []*config.Command, pathParts []string) (*config.Comman
 		return &config.Command{}, false
 	}
 	for _, cmd := range commands {
-		if cmd.Name == pathParts[0] {
+		if cmd.Name == pathParts[0] || hasAlias(cmd, pathParts[0]) {
 			if len(pathParts) == 1 {
 				return cmd, true
 			}
@@ -112,37 +210,79 @@ func findCommand(commands []*config.Command, pathParts []string) (*config.Comman
 	return &config.Command{}, false
 }
 
-func renderCommand(config *config.Schema, path string) string {
+func hasAlias(cmd *config.Command, name string) bool {
+	for _, alias := range cmd.Aliases {
+		if alias == name {
+			return true
+		}
+	}
+	return false
+}
+
+func renderCommand(config *config.Schema, path string, showHidden bool) string {
 	pathParts := strings.Split(strings.TrimPrefix(path, "/"), "/")
-	cmd, ok := findCommand(config.Commands, pathParts)
+	chain, ok := findCommandChain(config, pathParts)
 	if !ok {
 		return "Command not found"
 	}
+	cmd := chain[len(chain)-1]
 
 	var sb strings.Builder
 	commandName := strings.Join(pathParts, " ")
 	if config.Name != "" {
 		commandName = config.Name + " " + commandName
 	}
-	sb.WriteString(fmt.Sprintf(`<h1>%s</h1><p>%s</p>`,
-		html.EscapeString(commandName), html.EscapeString(cmd.Description)))
+	sb.WriteString(fmt.Sprintf(`<h1>%s%s</h1>`, html.EscapeString(commandName), renderBadges(&cmd.Lifecycle)))
+	sb.WriteString(renderDeprecation("This command", &cmd.Lifecycle))
+	sb.WriteString(fmt.Sprintf(`<p>%s</p>`, html.EscapeString(cmd.Description)))
 
 	sb.WriteString(`<form id="command-form">`)
 	// Render Arguments
 	if len(cmd.Arguments) > 0 {
 		sb.WriteString(`<h2>Arguments</h2>`)
 		for _, arg := range cmd.Arguments {
-			renderInput(&sb, "option", arg.Type, arg.Name, arg.Description, arg.Multiline, "arg-"+arg.Name, arg.Default)
+			renderInput(&sb, &inputField{
+				Type:        arg.Type,
+				DisplayName: arg.Name,
+				Description: arg.Description,
+				Multiline:   arg.Multiline,
+				Secret:      arg.Secret,
+				Choices:     arg.Choices,
+				Name:        "arg-" + arg.Name,
+				Default:     arg.Default,
+			})
 		}
 	}
 
 	// Render Options
-	if len(cmd.Options) > 0 {
+	options := visibleOptions(cmd.Options, showHidden)
+	if len(options) > 0 {
 		sb.WriteString(`<h2>Options</h2>`)
-		for _, opt := range cmd.Options {
-			renderInput(&sb, "option", opt.Type, opt.Flags, opt.Description, opt.Multiline, opt.Flags, opt.Default)
+		for _, group := range groupOptions(cmd.Groups, options) {
+			if group.Group == nil {
+				renderOptions(&sb, group.Options)
+				continue
+			}
+			open := " open"
+			if group.Group.Collapsed {
+				open = ""
+			}
+			sb.WriteString(fmt.Sprintf(`<details class="option-group"%s><summary>%s</summary><fieldset>`,
+				open, html.EscapeString(group.Group.Name)))
+			if group.Group.Description != "" {
+				sb.WriteString(fmt.Sprintf(`<p class="group-description">%s</p>`, html.EscapeString(group.Group.Description)))
+			}
+			renderOptions(&sb, group.Options)
+			sb.WriteString(`<button type="button" class="group-reset">Reset</button></fieldset></details>`)
 		}
 	}
+
+	// Render persistent options inherited from ancestors
+	globalOptions := visibleOptions(inheritedOptions(chain), showHidden)
+	if len(globalOptions) > 0 {
+		sb.WriteString(`<h2>Global options</h2>`)
+		renderOptions(&sb, globalOptions)
+	}
 	sb.WriteString(`<button type="submit">Run</button></form>`)
 	sb.WriteString(`<h2>Output</h2><pre id="output"></pre>`)
 	sb.WriteString(`<h2>Examples</h2>`)
@@ -154,36 +294,177 @@ func renderCommand(config *config.Schema, path string) string {
 	return sb.String()
 }
 
-func renderInput(sb *strings.Builder, wrapperClass string, inputType string, displayName string, description string, multiline bool, argName string, defaultValue string) {
+func renderOptions(sb *strings.Builder, options []*config.Option) {
+	for _, opt := range options {
+		spec := opt.Spec()
+		renderInput(sb, &inputField{
+			Type:        opt.Type,
+			DisplayName: spec.Display(),
+			Description: opt.Description,
+			Multiline:   opt.Multiline,
+			Secret:      opt.Secret,
+			Choices:     opt.Choices,
+			Name:        spec.ID(),
+			Default:     opt.Default,
+			Lifecycle:   &opt.Lifecycle,
+		})
+	}
+}
+
+// optionGroup is a group with its options, Group is nil for ungrouped options
+type optionGroup struct {
+	Group   *config.OptionGroup
+	Options []*config.Option
+}
+
+// groupOptions splits options by group: ungrouped options first, then
+// declared groups by order, then groups that are referenced but not declared.
+// Empty groups are dropped.
+func groupOptions(groups []*config.OptionGroup, options []*config.Option) []*optionGroup {
+	sorted := make([]*config.OptionGroup, len(groups))
+	copy(sorted, groups)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Order < sorted[j].Order
+	})
+
+	ungrouped := &optionGroup{}
+	result := []*optionGroup{ungrouped}
+	byName := make(map[string]*optionGroup, len(sorted))
+	for _, group := range sorted {
+		if byName[group.Name] != nil {
+			continue
+		}
+		g := &optionGroup{Group: group}
+		byName[group.Name] = g
+		result = append(result, g)
+	}
+	for _, opt := range options {
+		if opt.Group == "" {
+			ungrouped.Options = append(ungrouped.Options, opt)
+			continue
+		}
+		g := byName[opt.Group]
+		if g == nil {
+			g = &optionGroup{Group: &config.OptionGroup{Name: opt.Group}}
+			byName[opt.Group] = g
+			result = append(result, g)
+		}
+		g.Options = append(g.Options, opt)
+	}
+
+	nonEmpty := result[:0]
+	for _, g := range result {
+		if len(g.Options) > 0 {
+			nonEmpty = append(nonEmpty, g)
+		}
+	}
+	return nonEmpty
+}
+
+func inheritedOptions(chain []*config.Command) []*config.Option {
+	var options []*config.Option
+	for _, opt := range config.InheritedOptions(chain) {
+		options = append(options, opt.Option)
+	}
+	return options
+}
+
+func visibleOptions(options []*config.Option, showHidden bool) []*config.Option {
+	var visible []*config.Option
+	for _, opt := range options {
+		if !opt.Hidden || showHidden {
+			visible = append(visible, opt)
+		}
+	}
+	return visible
+}
+
+// inputField describes a single form input for an argument or option
+type inputField struct {
+	Type        string
+	DisplayName string
+	Description string
+	Multiline   bool
+	Secret      bool
+	Choices     []string
+	// Name is the form field name
+	Name    string
+	Default string
+	// Lifecycle is nil for arguments
+	Lifecycle *config.Lifecycle
+}
+
+func renderInput(sb *strings.Builder, field *inputField) {
+	wrapperClass := "option"
 	var wrapperStyle string
-	if multiline {
+	if field.Multiline && !field.Secret {
 		wrapperStyle = ` style="display:flex;flex-direction:column;"`
 	}
+	displayName := html.EscapeString(field.DisplayName)
+	if field.Lifecycle != nil {
+		if field.Lifecycle.Deprecated != "" {
+			wrapperClass += " deprecated"
+			displayName = `<s>` + displayName + `</s>`
+		}
+		displayName += renderBadges(field.Lifecycle)
+	}
 	sb.WriteString(fmt.Sprintf(`<div class="%s"%s>`, wrapperClass, wrapperStyle))
 	var descriptionHTML string
-	if description != "" {
-		descriptionHTML = " (" + html.EscapeString(description) + ")"
+	if field.Description != "" {
+		descriptionHTML = " (" + html.EscapeString(field.Description) + ")"
 	}
 
-	if inputType == "boolean" {
+	if field.Type == config.TypeBoolean {
 		sb.WriteString(fmt.Sprintf(`<label><input type="checkbox" name="%s"> %s%s</label>`,
-			html.EscapeString(displayName), html.EscapeString(argName), descriptionHTML))
+			html.EscapeString(field.Name), displayName, descriptionHTML))
 	} else {
 		sb.WriteString(fmt.Sprintf(`<label>%s%s: </label>`,
-			html.EscapeString(displayName), descriptionHTML))
+			displayName, descriptionHTML))
 
-		if multiline {
+		if len(field.Choices) > 0 {
+			sb.WriteString(fmt.Sprintf(`<select name="%s">`, html.EscapeString(field.Name)))
+			if field.Default == "" {
+				sb.WriteString(`<option value=""></option>`)
+			}
+			for _, choice := range field.Choices {
+				var selected string
+				if choice == field.Default {
+					selected = " selected"
+				}
+				sb.WriteString(fmt.Sprintf(`<option value="%s"%s>%s</option>`,
+					html.EscapeString(choice), selected, html.EscapeString(choice)))
+			}
+			sb.WriteString(`</select>`)
+		} else if field.Secret {
+			// secrets are never multiline so that they stay masked, their
+			// default is applied by the server, see formValue
+			var placeholder string
+			if field.Default != "" {
+				placeholder = ` placeholder="` + secretMask + `"`
+			}
+			sb.WriteString(fmt.Sprintf(`<input type="password" autocomplete="off" name="%s"%s>`,
+				html.EscapeString(field.Name), placeholder))
+		} else if field.Multiline {
 			sb.WriteString(fmt.Sprintf(`<textarea name="%s">%s</textarea>`,
-				html.EscapeString(argName), html.EscapeString(defaultValue)))
+				html.EscapeString(field.Name), html.EscapeString(field.Default)))
 		} else {
-			sb.WriteString(fmt.Sprintf(`<input type="text" name="%s" value="%s">`,
-				html.EscapeString(argName), html.EscapeString(defaultValue)))
+			htmlType := "text"
+			if field.Type == config.TypeNumber {
+				htmlType = "number"
+			}
+			sb.WriteString(fmt.Sprintf(`<input type="%s" name="%s" value="%s">`,
+				htmlType, html.EscapeString(field.Name), html.EscapeString(field.Default)))
 		}
 	}
+	if field.Lifecycle != nil {
+		sb.WriteString(renderDeprecation("This option", field.Lifecycle))
+	}
 	sb.WriteString(`</div>`)
 }
 
-func serveWs(w http.ResponseWriter, r *http.Request, config *config.Schema) {
+// serveWs runs the command at path of config with the
+// submitted form, streaming its output
+func serveWs(w http.ResponseWriter, r *http.Request, config *config.Schema, path string) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Println("Websocket upgrade error:", err)
@@ -204,7 +485,7 @@ func serveWs(w http.ResponseWriter, r *http.Request, config *config.Schema) {
 		return
 	}
 
-	rawPathParts := strings.Split(strings.TrimPrefix(r.URL.Path, "/ws"), "/")
+	rawPathParts := strings.Split(path, "/")
 	var pathParts []string
 	for _, part := range rawPathParts {
 		if part != "" {
@@ -212,37 +493,23 @@ func serveWs(w http.ResponseWriter, r *http.Request, config *config.Schema) {
 		}
 	}
 
-	cmd, ok := findCommand(config.Commands, pathParts)
+	chain, ok := findCommandChain(config, pathParts)
 	if !ok {
-		log.Println("Command not found for path:", r.URL.Path, "Parsed parts:", pathParts)
+		log.Println("Command not found for path:", path, "Parsed parts:", pathParts)
 		conn.Close()
 		return
 	}
 
-	var args []string
-	if config.Name != "" {
-		args = append(args, config.Name)
-	}
-	args = append(args, pathParts...)
-
-	// Add arguments
-	for _, arg := range cmd.Arguments {
-		if value := formData["arg-"+arg.Name]; value != "" {
-			args = append(args, value)
-		}
-	}
-
-	// Add options
-	for _, opt := range cmd.Options {
-		if opt.Type == "boolean" && formData[opt.Flags] == "on" {
-			args = append(args, opt.Flags)
-		} else if value, ok := formData[opt.Flags]; ok && value != "" && opt.Type == "string" {
-			args = append(args, opt.Flags, value)
-		}
+	if err := validateForm(chain, formData); err != nil {
+		log.Println("Invalid form:", err)
+		conn.WriteMessage(websocket.TextMessage, []byte("error: "+err.Error()+"\n"))
+		conn.Close()
+		return
 	}
+	inv := buildInvocation(chain, formData)
 
-	log.Printf("Executing command: %v", args)
-	cmdExec := exec.Command(args[0], args[1:]...)
+	log.Printf("Executing command: %s", inv)
+	cmdExec := inv.Command()
 
 	stdout, err := cmdExec.StdoutPipe()
 	if err != nil {
@@ -267,8 +534,8 @@ func serveWs(w http.ResponseWriter, r *http.Request, config *config.Schema) {
 	var wg sync.WaitGroup
 	wg.Add(2) // Wait for stdout and stderr goroutines
 
-	go streamOutput(conn, stdout, "stdout", &wg)
-	go streamOutput(conn, stderr, "stderr", &wg)
+	go streamOutput(conn, stdout, "stdout", inv, &wg)
+	go streamOutput(conn, stderr, "stderr", inv, &wg)
 
 	log.Println("Waiting for command to finish...")
 	if err := cmdExec.Wait(); err != nil {
@@ -279,12 +546,12 @@ func serveWs(w http.ResponseWriter, r *http.Request, config *config.Schema) {
 	conn.Close()
 }
 
-func streamOutput(conn *websocket.Conn, reader io.Reader, streamType string, wg *sync.WaitGroup) {
+func streamOutput(conn *websocket.Conn, reader io.Reader, streamType string, inv *invocation, wg *sync.WaitGroup) {
 	defer wg.Done()
 	scanner := bufio.NewScanner(reader)
 	for scanner.Scan() {
 		line := scanner.Bytes()
-		log.Printf("[%s] Sending: %s", streamType, string(line))
+		log.Printf("[%s] Sending: %s", streamType, inv.Mask(string(line)))
 		if err := conn.WriteMessage(websocket.TextMessage, append(line, '\n')); err != nil {
 			log.Println("Websocket write message error:", err)
 			return
@@ -294,102 +561,123 @@ func streamOutput(conn *websocket.Conn, reader io.Reader, streamType string, wg
 }
 
 func runArgs(args []string) error {
-	var schemaPath string
+	var schemaPaths []string
+	var schemasDir string
 	var port int
+	var showHidden bool
 
-	origArgs := args
-	args, err := flags.String("--schema", &schemaPath).
-		Bool("--port", &port).
-		Help("-h,--help", help).
-		Parse(args)
-	if err != nil {
-		return err
-	}
-
-	if len(args) > 0 {
-		cmd := origArgs[0]
-		cmdArgs := origArgs[1:]
+	// subcommands have their own flags
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd := args[0]
+		cmdArgs := args[1:]
 		switch cmd {
 		case "parse-schema":
 			return handleParseSchema(cmdArgs)
+		case "import-help":
+			return handleImportHelp(cmdArgs)
+		case "export-dir":
+			return handleExportDir(cmdArgs)
+		case "fmt-schema":
+			return handleFmtSchema(cmdArgs)
+		case "docs":
+			return handleDocs(cmdArgs)
+		case "jsonschema":
+			return handleJSONSchema(cmdArgs)
+		case "completion":
+			return handleCompletion(cmdArgs)
+		case "tui":
+			return handleTUI(cmdArgs)
 		case "example":
 			return handleExample(cmdArgs)
 		}
 		return fmt.Errorf("unrecognized command: %s", cmd)
 	}
 
-	var configData []byte
-	if schemaPath == "" {
-		if IsStdinTTY() {
-			return fmt.Errorf("requires --schema, try `cli2web --help`")
-		}
-		// read schema from stdin
-		configData, err = io.ReadAll(os.Stdin)
-		if err != nil {
-			return fmt.Errorf("reading schema from stdin: %v", err)
+	args, err := flags.StringSlice("--schema", &schemaPaths).
+		String("--schemas", &schemasDir).
+		Int("--port", &port).
+		Bool("--show-hidden", &showHidden).
+		Help("-h,--help", help).
+		Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return fmt.Errorf("unrecognized arguments: %s", strings.Join(args, " "))
+	}
+
+	if len(schemaPaths) > 0 || schemasDir != "" {
+		// Read schemas, parsed as json, yaml or toml, or from directories
+		var schemas []*config.Schema
+		for _, schemaPath := range schemaPaths {
+			s, err := loadSchema(schemaPath)
+			if err != nil {
+				return err
+			}
+			schemas = append(schemas, s)
 		}
-	} else {
-		// Read and parse JSON schema
-		configData, err = os.ReadFile(schemaPath)
-		if err != nil {
-			return fmt.Errorf("reading schema file: %v", err)
+		if schemasDir != "" {
+			dirSchemas, err := loadSchemaDir(schemasDir)
+			if err != nil {
+				return err
+			}
+			schemas = append(schemas, dirSchemas...)
 		}
+		return runConfig(RunOptions{
+			Schemas:    schemas,
+			Port:       port,
+			ShowHidden: showHidden,
+		})
 	}
 
-	return runConfig(configData, nil, port)
+	if IsStdinTTY() {
+		return fmt.Errorf("requires --schema, try `cli2web --help`")
+	}
+	// read schema from stdin
+	configData, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return fmt.Errorf("reading schema from stdin: %v", err)
+	}
+	return runConfig(RunOptions{
+		Schema:       configData,
+		SchemaFormat: schema.DetectFormat("", configData),
+		Port:         port,
+		ShowHidden:   showHidden,
+	})
 }
 
-func runConfig(configData []byte, schemaConfig *config.Schema, port int) error {
-	var config *config.Schema
-	if schemaConfig != nil {
-		config = schemaConfig
-	} else {
-		if err := json.Unmarshal(configData, &config); err != nil {
+func runConfig(opts RunOptions) error {
+	var schemas []*config.Schema
+	if opts.SchemaConfig != nil {
+		schemas = append(schemas, opts.SchemaConfig)
+	} else if opts.Schema != nil || len(opts.Schemas) == 0 {
+		format := opts.SchemaFormat
+		if format == "" {
+			format = schema.DetectFormat("", opts.Schema)
+		}
+		dir, file := ".", ""
+		if opts.SchemaPath != "" {
+			dir, file = filepath.Dir(opts.SchemaPath), filepath.Base(opts.SchemaPath)
+		}
+		s, err := schema.UnmarshalSchema(opts.Schema, format, schema.NewFSSchemaDir(dir), file)
+		if err != nil {
 			return fmt.Errorf("parsing schema file: %v", err)
 		}
+		if s != nil {
+			schemas = append(schemas, s)
+		}
+	}
+	schemas = append(schemas, opts.Schemas...)
+	mounts, err := mountSchemas(schemas)
+	if err != nil {
+		return err
 	}
 
 	// Serve static files
 	// http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
+	handler := newServer(mounts, opts.ShowHidden).handler()
 
-	renderPage := func(title, content string) string {
-		return `<!DOCTYPE html><html><head><title>` + html.EscapeString(title) + `</title>` +
-			// `<link rel="stylesheet" href="/static/style.css">` +
-			`<style>` + styleCSS + `</style>` +
-			`</head><body><div class="container">` +
-			renderSidebar(config) +
-			`<div class="main-content">` + content + `</div></div>` +
-			// `<script src="/static/script.js"></script>` +
-			`<script>` + scriptJS + `</script>` +
-			`</body></html>`
-	}
-
-	// Home page and command pages
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/" {
-			// Command page
-			pathParts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
-			title := strings.Join(pathParts, " ")
-			if config.Name != "" {
-				title = config.Name + " " + title
-			}
-			fmt.Fprint(w, renderPage(title, renderCommand(config, r.URL.Path)))
-			return
-		}
-
-		// Home page
-		webTitle := "CLI Web Interface"
-		if config.Name != "" {
-			webTitle = config.Name + " Web Interface"
-		}
-		content := `<h1>` + html.EscapeString(webTitle) + `</h1><p>Select a command from the sidebar to begin.</p>`
-		fmt.Fprint(w, renderPage(webTitle, content))
-	})
-
-	http.HandleFunc("/ws/", func(w http.ResponseWriter, r *http.Request) {
-		serveWs(w, r, config)
-	})
-
+	port := opts.Port
 	if port == 0 {
 		listenPort, err := netport.FindListenablePort("", 7777, 100)
 		if err != nil {
@@ -428,7 +716,7 @@ func runConfig(configData []byte, schemaConfig *config.Schema, port int) error {
 		}
 	}()
 
-	if err := http.ListenAndServe(listenAddr, nil); err != nil {
+	if err := http.ListenAndServe(listenAddr, handler); err != nil {
 		return fmt.Errorf("server error: %v", err)
 	}
 	return nil
@@ -460,20 +748,31 @@ func handleParseSchema(args []string) error {
 		if err != nil {
 			return fmt.Errorf("reading schema file: %v", err)
 		}
-		var schema *config.Schema
-		if err := json.Unmarshal(data, &schema); err != nil {
+		_, diagnostics, err := schema.ValidateData(data, schema.DetectFormat(file, data), file)
+		if err != nil {
 			return fmt.Errorf("parsing schema file: %v", err)
 		}
+		if err := reportDiagnostics(diagnostics); err != nil {
+			return err
+		}
 		fmt.Printf("validated\n")
 		return nil
 	}
 	dir := file
-	schema, err := schema.ParseSchemaFromDir(dir)
+	s, diagnostics, err := schema.ValidateDir(schema.NewFSSchemaDir(dir))
 	if err != nil {
-		return fmt.Errorf("parsing schema file: %v", err)
+		return schemaDirError(err)
+	}
+	for _, d := range diagnostics {
+		if d.File != "" {
+			d.File = filepath.Join(dir, d.File)
+		}
+	}
+	if err := reportDiagnostics(diagnostics); err != nil {
+		return err
 	}
 
-	printSchema, err := json.MarshalIndent(schema, "", "  ")
+	printSchema, err := json.MarshalIndent(s, "", "  ")
 	if err != nil {
 		return fmt.Errorf("marshaling schema: %v", err)
 	}
@@ -481,6 +780,187 @@ func handleParseSchema(args []string) error {
 	return nil
 }
 
+// reportDiagnostics prints diagnostics to stderr, returns
+// an error if there is any
+func reportDiagnostics(diagnostics []*schema.Diagnostic) error {
+	if len(diagnostics) == 0 {
+		return nil
+	}
+	for _, d := range diagnostics {
+		fmt.Fprintln(os.Stderr, d.String())
+	}
+	return fmt.Errorf("found %d problem(s) in schema", len(diagnostics))
+}
+
+const importHelpHelp = `
+Generate a schema by running <cmd> --help, and recursively
+<cmd> <sub> --help for each listed subcommand
+
+Usage: cli2web import-help [options] -- <cmd> [args...]
+
+Options:
+  --depth <n>                 subcommand levels to visit, default 5
+  --format json|dir           output format, default json
+  -o,--output <path>          output file for json, required directory for dir
+`
+
+func handleImportHelp(args []string) error {
+	depth := importhelp.DefaultDepth
+	var format string
+	var output string
+	args, err := flags.Int("--depth", &depth).
+		String("--format", &format).
+		String("-o,--output", &output).
+		Help("-h,--help", importHelpHelp).
+		Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("requires command, try `cli2web import-help --help`")
+	}
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "dir" {
+		return fmt.Errorf("unrecognized format: %s, expect json or dir", format)
+	}
+	if format == "dir" && output == "" {
+		return fmt.Errorf("--format dir requires -o <dir>")
+	}
+
+	s, err := importhelp.Import(args, importhelp.Options{Depth: depth})
+	if err != nil {
+		return err
+	}
+	if format == "dir" {
+		return schema.WriteSchemaDir(s, output)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling schema: %v", err)
+	}
+	if output == "" {
+		fmt.Println(string(data))
+		return nil
+	}
+	return os.WriteFile(output, append(data, '\n'), 0644)
+}
+
+func handleExportDir(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: cli2web export-dir <schema.json> <outdir>")
+	}
+	file, outDir := args[0], args[1]
+	s, err := loadSchema(file)
+	if err != nil {
+		return err
+	}
+	if s.Name == "" {
+		// the root file is named after the command
+		s.Name = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	}
+	return schema.WriteSchemaDir(s, outDir)
+}
+
+const fmtSchemaHelp = `
+Format the markdown files of a schema directory in place: section
+titles are cased like "Options", JSON blocks are reindented, options
+are sorted by name and tables are aligned. Prints the changed files.
+
+Usage: cli2web fmt-schema [options] <dir>
+
+Options:
+  --check                     only list files that are not formatted, fail if any
+`
+
+func handleFmtSchema(args []string) error {
+	var check bool
+	args, err := flags.Bool("--check", &check).
+		Help("-h,--help", fmtSchemaHelp).
+		Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("usage: cli2web fmt-schema [--check] <dir>")
+	}
+	dir := args[0]
+	changed, err := schema.FormatSchemaDir(dir, !check)
+	if err != nil {
+		return schemaDirError(err)
+	}
+	for _, file := range changed {
+		fmt.Println(filepath.Join(dir, file))
+	}
+	if check && len(changed) > 0 {
+		return fmt.Errorf("%d file(s) not formatted", len(changed))
+	}
+	return nil
+}
+
+// loadSchema reads a json, yaml or toml schema file, or a schema directory
+func loadSchema(file string) (*config.Schema, error) {
+	stat, err := os.Stat(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading schema file: %v", err)
+	}
+	if stat.IsDir() {
+		s, err := schema.ParseSchemaFromDir(file)
+		if err != nil {
+			return nil, schemaDirError(err)
+		}
+		return s, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading schema file: %v", err)
+	}
+	s, err := schema.UnmarshalSchema(data, schema.DetectFormat(file, data), schema.NewFSSchemaDir(filepath.Dir(file)), filepath.Base(file))
+	if err != nil {
+		return nil, fmt.Errorf("parsing schema file: %v", err)
+	}
+	if s == nil {
+		return nil, fmt.Errorf("empty schema file: %s", file)
+	}
+	return s, nil
+}
+
+// schemaDirError reports a syntax error located in a markdown file
+// on its own, the position already tells where it is
+func schemaDirError(err error) error {
+	var parseErr *schema.ParseError
+	if errors.As(err, &parseErr) {
+		return parseErr
+	}
+	return fmt.Errorf("parsing schema file: %v", err)
+}
+
+func handleJSONSchema(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unrecognized extra arguments: %s", strings.Join(args, ", "))
+	}
+	_, err := os.Stdout.Write(schema.GenerateJSONSchema())
+	return err
+}
+
+func handleCompletion(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: cli2web completion bash|zsh|fish|powershell <schema>")
+	}
+	shell, file := args[0], args[1]
+	s, err := loadSchema(file)
+	if err != nil {
+		return err
+	}
+	script, err := completion.Generate(s, shell)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(os.Stdout, script)
+	return err
+}
+
 func handleExample(args []string) error {
 	fmt.Printf("example not implemented yet")
 	return nil
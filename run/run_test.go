@@ -0,0 +1,169 @@
+package run
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/cli2web/config"
+)
+
+func TestGroupOptions(t *testing.T) {
+	groups := []*config.OptionGroup{
+		{Name: "Output", Order: 2},
+		{Name: "Advanced", Order: 1, Collapsed: true},
+		{Name: "Empty"},
+	}
+	options := []*config.Option{
+		{Flags: "--format", Group: "Output"},
+		{Flags: "--verbose"},
+		{Flags: "--jobs", Group: "Advanced"},
+		{Flags: "--color", Group: "Output"},
+		{Flags: "--trace", Group: "Debug"},
+	}
+
+	var got []string
+	for _, g := range groupOptions(groups, options) {
+		name := "-"
+		if g.Group != nil {
+			name = g.Group.Name
+		}
+		var flags []string
+		for _, opt := range g.Options {
+			flags = append(flags, opt.Flags)
+		}
+		got = append(got, name+":"+strings.Join(flags, ","))
+	}
+	expected := "-:--verbose Advanced:--jobs Output:--format,--color Debug:--trace"
+	if strings.Join(got, " ") != expected {
+		t.Errorf("groupOptions() = %q, expected %q", strings.Join(got, " "), expected)
+	}
+}
+
+func TestRenderCommand_Choices(t *testing.T) {
+	s := &config.Schema{
+		Name: "kool",
+		Commands: []*config.Command{{
+			Name:      "build",
+			Arguments: []*config.Argument{{Name: "target", Choices: []string{"linux", "darwin"}}},
+			Options: []*config.Option{
+				{Flags: "--format", Choices: []string{"text", "json"}, Default: "json"},
+			},
+		}},
+	}
+	page := renderCommand(s, "/build", false)
+	for _, expected := range []string{
+		// no default, an empty choice comes first
+		`<select name="arg-target"><option value=""></option><option value="linux">linux</option><option value="darwin">darwin</option></select>`,
+		`<select name="opt-format"><option value="text">text</option><option value="json" selected>json</option></select>`,
+	} {
+		if !strings.Contains(page, expected) {
+			t.Errorf("expect page to contain %s, got %s", expected, page)
+		}
+	}
+}
+
+func TestRenderCommand_SecretDefault(t *testing.T) {
+	s := &config.Schema{
+		Name: "kool",
+		Commands: []*config.Command{{
+			Name:      "login",
+			Arguments: []*config.Argument{{Name: "pin", Secret: true, Default: "4242"}},
+			Options: []*config.Option{
+				{Flags: "--token <token>", Type: config.TypeString, Secret: true, Default: "tok123"},
+				{Flags: "--key <key>", Type: config.TypeString, Secret: true},
+			},
+		}},
+	}
+	page := renderCommand(s, "/login", false)
+	for _, secret := range []string{"4242", "tok123"} {
+		if strings.Contains(page, secret) {
+			t.Errorf("page leaks secret default %q: %s", secret, page)
+		}
+	}
+	for _, expected := range []string{
+		`<input type="password" autocomplete="off" name="opt-token" placeholder="******">`,
+		`<input type="password" autocomplete="off" name="opt-key">`,
+	} {
+		if !strings.Contains(page, expected) {
+			t.Errorf("expect page to contain %s, got %s", expected, page)
+		}
+	}
+}
+
+func TestFindCommand_Alias(t *testing.T) {
+	s := &config.Schema{
+		Name: "kool",
+		Commands: []*config.Command{{
+			Name:     "git",
+			Commands: []*config.Command{{Name: "tag-next", Aliases: []string{"tn"}}},
+		}},
+	}
+	cmd, ok := findCommand(s.Commands, []string{"git", "tn"})
+	if !ok || cmd.Name != "tag-next" {
+		t.Errorf("findCommand(git tn) = %v, %v, expected tag-next", cmd.Name, ok)
+	}
+	if _, ok := findCommand(s.Commands, []string{"git", "tag"}); ok {
+		t.Errorf("expect git tag not found")
+	}
+}
+
+func TestRenderSidebar_Order(t *testing.T) {
+	s := &config.Schema{
+		Name: "kool",
+		Commands: []*config.Command{
+			{Name: "help", Order: 1},
+			{Name: "git"},
+			{Name: "go", Order: -1},
+			{Name: "version"},
+		},
+	}
+	sidebar := renderSidebar(s, false)
+	indexOf := func(name string) int {
+		return strings.Index(sidebar, `href="/`+name+`"`)
+	}
+	for i, pair := range [][2]string{{"go", "git"}, {"git", "version"}, {"version", "help"}} {
+		if indexOf(pair[0]) < 0 || indexOf(pair[0]) > indexOf(pair[1]) {
+			t.Errorf("pair %d: expect %s before %s in %s", i, pair[0], pair[1], sidebar)
+		}
+	}
+	// the schema itself keeps its declaration order
+	if s.Commands[0].Name != "help" {
+		t.Errorf("expect commands left as is, got %s first", s.Commands[0].Name)
+	}
+}
+
+func TestRenderCommand_Groups(t *testing.T) {
+	s := &config.Schema{
+		Name: "kool",
+		Commands: []*config.Command{{
+			Name: "build",
+			Groups: []*config.OptionGroup{
+				{Name: "Output", Description: "Where results go"},
+				{Name: "Advanced", Collapsed: true},
+			},
+			Options: []*config.Option{
+				{Flags: "--verbose", Type: config.TypeBoolean},
+				{Flags: "--format", Group: "Output"},
+				{Flags: "--jobs", Group: "Advanced"},
+			},
+		}},
+	}
+	page := renderCommand(s, "/build", false)
+	for _, expected := range []string{
+		`<details class="option-group" open><summary>Output</summary><fieldset><p class="group-description">Where results go</p>`,
+		// collapsed groups are rendered closed
+		`<details class="option-group"><summary>Advanced</summary><fieldset>`,
+		`<input type="text" name="opt-jobs" value=""></div><button type="button" class="group-reset">Reset</button></fieldset></details>`,
+	} {
+		if !strings.Contains(page, expected) {
+			t.Errorf("expect page to contain %s, got %s", expected, page)
+		}
+	}
+	// ungrouped options come first, outside any group
+	if verbose, group := strings.Index(page, `name="opt-verbose"`), strings.Index(page, `<details`); verbose < 0 || verbose > group {
+		t.Errorf("expect ungrouped --verbose before the groups, got %s", page)
+	}
+	if strings.Count(page, `class="group-reset"`) != 2 {
+		t.Errorf("expect a reset button per group, got %s", page)
+	}
+}
@@ -0,0 +1,214 @@
+package run
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xhd2015/cli2web/config"
+)
+
+// mount is a schema served by the web interface
+type mount struct {
+	schema *config.Schema
+	// prefix is the URL path its pages are under, like "/kool",
+	// empty when it is the only schema served
+	prefix string
+}
+
+// mountSchemas mounts each schema under "/" + its name, a single
+// schema is served from "/" as is. The names are the executables
+// run, so they must be set and distinct when there are several.
+func mountSchemas(schemas []*config.Schema) ([]*mount, error) {
+	if len(schemas) == 0 {
+		return nil, fmt.Errorf("empty schema")
+	}
+	if len(schemas) == 1 {
+		return []*mount{{schema: schemas[0]}}, nil
+	}
+	byName := make(map[string]bool, len(schemas))
+	mounts := make([]*mount, 0, len(schemas))
+	// tools are ordered like sibling commands
+	for _, s := range config.SortedCommands(schemas) {
+		switch {
+		case s.Name == "":
+			return nil, fmt.Errorf("a schema has no name, required to serve several schemas")
+		case s.Name == "ws":
+			return nil, fmt.Errorf("schema name %s is reserved", s.Name)
+		case url.PathEscape(s.Name) != s.Name:
+			return nil, fmt.Errorf("schema name %q is not URL safe", s.Name)
+		case byName[s.Name]:
+			return nil, fmt.Errorf("several schemas are named %s", s.Name)
+		}
+		byName[s.Name] = true
+		mounts = append(mounts, &mount{schema: s, prefix: "/" + s.Name})
+	}
+	return mounts, nil
+}
+
+// loadSchemaDir loads each json, yaml or toml file and each
+// directory in dir as a schema, by file name. Names starting
+// with "_" or "." are skipped, e.g. "_shared/" for files that
+// schemas refer to.
+func loadSchemaDir(dir string) ([]*config.Schema, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading schema directory: %v", err)
+	}
+	var schemas []*config.Schema
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, "_") || strings.HasPrefix(name, ".") {
+			continue
+		}
+		if !entry.IsDir() {
+			switch strings.ToLower(filepath.Ext(name)) {
+			case ".json", ".yaml", ".yml", ".toml":
+			default:
+				continue
+			}
+		}
+		s, err := loadSchema(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		schemas = append(schemas, s)
+	}
+	if len(schemas) == 0 {
+		return nil, fmt.Errorf("no schema found in %s", dir)
+	}
+	return schemas, nil
+}
+
+// server serves the pages of the mounted schemas and runs
+// their commands
+type server struct {
+	mounts     []*mount
+	showHidden bool
+}
+
+func newServer(mounts []*mount, showHidden bool) *server {
+	return &server{mounts: mounts, showHidden: showHidden}
+}
+
+// handler routes pages to "/" and command runs to "/ws/"
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.servePage)
+	mux.HandleFunc("/ws/", func(w http.ResponseWriter, r *http.Request) {
+		m, path := s.find(strings.TrimPrefix(r.URL.Path, "/ws"))
+		if m == nil {
+			log.Println("No schema for path:", r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		serveWs(w, r, m.schema, path)
+	})
+	return mux
+}
+
+// find returns the mount serving urlPath and the path of
+// the command in it, "/" for its home page
+func (s *server) find(urlPath string) (*mount, string) {
+	for _, m := range s.mounts {
+		if m.prefix == "" {
+			return m, urlPath
+		}
+		if urlPath == m.prefix || strings.HasPrefix(urlPath, m.prefix+"/") {
+			path := strings.TrimPrefix(urlPath, m.prefix)
+			if path == "" {
+				path = "/"
+			}
+			return m, path
+		}
+	}
+	return nil, ""
+}
+
+func (s *server) servePage(w http.ResponseWriter, r *http.Request) {
+	m, path := s.find(r.URL.Path)
+	if m == nil {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		title := "CLI Web Interface"
+		fmt.Fprint(w, s.renderPage(title, `<h1>`+title+`</h1>`+s.renderTools()))
+		return
+	}
+	cfg := m.schema
+	if path != "/" {
+		// Command page
+		pathParts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+		title := strings.Join(pathParts, " ")
+		if cfg.Name != "" {
+			title = cfg.Name + " " + title
+		}
+		fmt.Fprint(w, s.renderPage(title, renderCommand(cfg, path, s.showHidden)))
+		return
+	}
+
+	// Home page
+	webTitle := "CLI Web Interface"
+	if cfg.Name != "" {
+		webTitle = cfg.Name + " Web Interface"
+	}
+	content := `<h1>` + html.EscapeString(webTitle) + `</h1><p>Select a command from the sidebar to begin.</p>`
+	fmt.Fprint(w, s.renderPage(webTitle, content))
+}
+
+func (s *server) renderPage(title, content string) string {
+	return `<!DOCTYPE html><html><head><title>` + html.EscapeString(title) + `</title>` +
+		// `<link rel="stylesheet" href="/static/style.css">` +
+		`<style>` + styleCSS + `</style>` +
+		`</head><body><div class="container">` +
+		s.renderSidebar() +
+		`<div class="main-content">` + content + `</div></div>` +
+		// `<script src="/static/script.js"></script>` +
+		`<script>` + scriptJS + `</script>` +
+		`</body></html>`
+}
+
+// renderSidebar renders a section per mounted schema
+func (s *server) renderSidebar() string {
+	if len(s.mounts) == 1 && s.mounts[0].prefix == "" {
+		return renderSidebar(s.mounts[0].schema, s.showHidden)
+	}
+	var sb strings.Builder
+	sb.WriteString(`<div class="sidebar">`)
+	for _, m := range s.mounts {
+		if m.schema.Hidden && !s.showHidden {
+			continue
+		}
+		renderSidebarSection(&sb, m.schema, s.showHidden, m.prefix, m.prefix+"/", func(path string) string {
+			return path
+		})
+	}
+	sb.WriteString(`</div>`)
+	return sb.String()
+}
+
+// renderTools renders the landing page list of mounted schemas
+func (s *server) renderTools() string {
+	var sb strings.Builder
+	sb.WriteString(`<p>Select a tool to begin.</p><ul class="tools">`)
+	for _, m := range s.mounts {
+		cfg := m.schema
+		if cfg.Hidden && !s.showHidden {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf(`<li><a href="%s/">%s</a>%s`,
+			html.EscapeString(m.prefix), html.EscapeString(cfg.Name), renderBadges(&cfg.Lifecycle)))
+		if cfg.Description != "" {
+			sb.WriteString(": " + html.EscapeString(cfg.Description))
+		}
+		sb.WriteString(`</li>`)
+	}
+	sb.WriteString(`</ul>`)
+	return sb.String()
+}
@@ -0,0 +1,147 @@
+package run
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/cli2web/config"
+)
+
+func testMounts(t *testing.T) []*mount {
+	t.Helper()
+	mounts, err := mountSchemas([]*config.Schema{
+		{
+			Name:        "kool",
+			Description: "Amend cli utilities",
+			Commands: []*config.Command{
+				{Name: "git", Commands: []*config.Command{{Name: "tag-next", Description: "Get the next git tag"}}},
+			},
+		},
+		{
+			Name:     "deployctl",
+			Order:    -1,
+			Commands: []*config.Command{{Name: "rollout", Description: "Roll out a release"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("mountSchemas() error = %v", err)
+	}
+	return mounts
+}
+
+func getPage(t *testing.T, handler http.Handler, path string) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	body, _ := io.ReadAll(rec.Result().Body)
+	return rec.Code, string(body)
+}
+
+func TestServer_Mounts(t *testing.T) {
+	handler := newServer(testMounts(t), false).handler()
+
+	code, landing := getPage(t, handler, "/")
+	if code != http.StatusOK {
+		t.Fatalf("GET / = %d", code)
+	}
+	// ordered by the order field
+	deployctl, kool := strings.Index(landing, `<a href="/deployctl/">deployctl</a>`), strings.Index(landing, `<a href="/kool/">kool</a>: Amend cli utilities`)
+	if deployctl < 0 || kool < 0 || deployctl > kool {
+		t.Errorf("expect landing page to list deployctl then kool, got %s", landing)
+	}
+	for _, expected := range []string{
+		`<h2><a href="/kool/">kool Commands</a></h2>`,
+		`data-path="/kool/git"`,
+		`<a href="/kool/git/tag-next">tag-next</a>`,
+		`<a href="/deployctl/rollout">rollout</a>`,
+	} {
+		if !strings.Contains(landing, expected) {
+			t.Errorf("expect sidebar to contain %s", expected)
+		}
+	}
+
+	_, home := getPage(t, handler, "/kool/")
+	if !strings.Contains(home, "<h1>kool Web Interface</h1>") {
+		t.Errorf("unexpected kool home page %s", home)
+	}
+	_, page := getPage(t, handler, "/kool/git/tag-next")
+	if !strings.Contains(page, "<title>kool git tag-next</title>") || !strings.Contains(page, "<h1>kool git tag-next</h1>") {
+		t.Errorf("unexpected command page %s", page)
+	}
+	if code, _ := getPage(t, handler, "/dbtool/"); code != http.StatusNotFound {
+		t.Errorf("GET /dbtool/ = %d, expected 404", code)
+	}
+}
+
+func TestServer_SingleSchema(t *testing.T) {
+	mounts, err := mountSchemas([]*config.Schema{{
+		Name:     "kool",
+		Commands: []*config.Command{{Name: "version"}},
+	}})
+	if err != nil {
+		t.Fatalf("mountSchemas() error = %v", err)
+	}
+	handler := newServer(mounts, false).handler()
+	_, home := getPage(t, handler, "/")
+	if !strings.Contains(home, "<h1>kool Web Interface</h1>") || !strings.Contains(home, `<a href="/version">version</a>`) {
+		t.Errorf("expect a single schema served from /, got %s", home)
+	}
+}
+
+func TestMountSchemas_Errors(t *testing.T) {
+	tests := []struct {
+		names    []string
+		expected string
+	}{
+		{[]string{"kool", ""}, "a schema has no name"},
+		{[]string{"kool", "kool"}, "several schemas are named kool"},
+		{[]string{"kool", "ws"}, "schema name ws is reserved"},
+		{[]string{"kool", "db tool"}, `schema name "db tool" is not URL safe`},
+	}
+	for _, tt := range tests {
+		var schemas []*config.Schema
+		for _, name := range tt.names {
+			schemas = append(schemas, &config.Schema{Name: name})
+		}
+		_, err := mountSchemas(schemas)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("mountSchemas(%q) error = %v, expected %q", tt.names, err, tt.expected)
+		}
+	}
+}
+
+func TestLoadSchemaDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"kool.json":           `{"name": "kool"}`,
+		"deployctl.yaml":      "name: deployctl\n",
+		"dbtool/_index.md":    "# Description\nDatabase tool\n",
+		"_shared/common.json": `{"definitions": {}}`,
+		"README.txt":          "not a schema",
+	}
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	schemas, err := loadSchemaDir(dir)
+	if err != nil {
+		t.Fatalf("loadSchemaDir() error = %v", err)
+	}
+	var names []string
+	for _, s := range schemas {
+		names = append(names, s.Name)
+	}
+	if got, expected := strings.Join(names, " "), "dbtool deployctl kool"; got != expected {
+		t.Errorf("loadSchemaDir() = %s, expected %s", got, expected)
+	}
+}
@@ -27,11 +27,30 @@ document.addEventListener('DOMContentLoaded', function () {
         }
     });
 
+    // Reset the inputs of an option group to their defaults
+    document.addEventListener('click', function (event) {
+        if (event.target.classList.contains('group-reset')) {
+            const group = event.target.closest('.option-group');
+            group.querySelectorAll('input, textarea, select').forEach(function (input) {
+                if (input.type === 'checkbox') {
+                    input.checked = input.defaultChecked;
+                } else if (input.tagName === 'SELECT') {
+                    Array.from(input.options).forEach(function (option) {
+                        option.selected = option.defaultSelected;
+                    });
+                } else {
+                    input.value = input.defaultValue;
+                }
+            });
+        }
+    });
+
     // Expand the tree to the current page
-    var path = window.location.pathname;
+    // compare resolved hrefs, static docs use relative links
+    var current = window.location.href.split(/[?#]/)[0];
     var links = document.querySelectorAll('.sidebar a');
     links.forEach(function(link) {
-        if (link.getAttribute('href') === path) {
+        if (link.href === current) {
             var parent = link.parentElement;
             while (parent) {
                 if (parent.classList.contains('nested')) {
@@ -0,0 +1,837 @@
+package run
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xhd2015/cli2web/config"
+	"github.com/xhd2015/less-gen/flags"
+	"golang.org/x/term"
+)
+
+const tuiHelp = `
+Usage: cli2web tui --schema <file>
+
+Browse and run commands in a full-screen terminal interface.
+
+Options:
+  --schema <file>            path to the schema file or directory
+  --show-hidden              also show hidden commands and options
+
+Keys:
+  tab, shift-tab             switch between commands, form and output
+  up, down                   move, scroll the output
+  enter, right, left         expand or collapse commands, open a form
+  space, left, right         toggle checkboxes, cycle choices
+  ctrl-r                     run the command
+  ctrl-c                     stop the running command, or quit
+  q                          quit from the commands or the output
+`
+
+func handleTUI(args []string) error {
+	var schemaPath string
+	var showHidden bool
+	args, err := flags.String("--schema", &schemaPath).
+		Bool("--show-hidden", &showHidden).
+		Help("-h,--help", tuiHelp).
+		Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return fmt.Errorf("unrecognized arguments: %s", strings.Join(args, " "))
+	}
+	if schemaPath == "" {
+		return fmt.Errorf("requires --schema, try `cli2web tui --help`")
+	}
+	s, err := loadSchema(schemaPath)
+	if err != nil {
+		return err
+	}
+	if !IsStdinTTY() || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return fmt.Errorf("tui requires a terminal")
+	}
+	return runTUI(newTUI(s, showHidden))
+}
+
+type tuiFocus int
+
+const (
+	focusTree tuiFocus = iota
+	focusForm
+	focusOutput
+)
+
+// tuiNode is a command listed in the tree
+type tuiNode struct {
+	chain []*config.Command
+	depth int
+}
+
+func (n *tuiNode) cmd() *config.Command {
+	return n.chain[len(n.chain)-1]
+}
+
+// key identifies the command by its path, e.g. "git tag-next"
+func (n *tuiNode) key() string {
+	var names []string
+	for _, c := range n.chain[1:] {
+		names = append(names, c.Name)
+	}
+	return strings.Join(names, " ")
+}
+
+// tuiField is a form input for an argument or option
+type tuiField struct {
+	section     string
+	label       string
+	description string
+	// name is the form field name, the same as the web form
+	name    string
+	typ     string
+	choices []string
+	secret  bool
+}
+
+// cycleValues lists the values a choice field cycles through, an empty
+// value comes first when there is no default, like the web select
+func (f *tuiField) cycleValues(defaultValue string) []string {
+	if defaultValue == "" {
+		return append([]string{""}, f.choices...)
+	}
+	return f.choices
+}
+
+type tuiAction int
+
+const (
+	actionNone tuiAction = iota
+	actionRun
+)
+
+// tui is the state of the terminal interface. It is only accessed
+// from the event loop, keys and output lines are fed to it in order.
+type tui struct {
+	schema     *config.Schema
+	showHidden bool
+
+	focus      tuiFocus
+	expanded   map[string]bool
+	treeCursor int
+
+	// selected is the command whose form is shown
+	selected *tuiNode
+	fields   []*tuiField
+	// formCursor is the focused field, len(fields) is the run button
+	formCursor int
+	// values holds the form data of each visited command by key
+	values map[string]map[string]string
+
+	output []string
+	// outputScroll is the number of lines scrolled up from the bottom
+	outputScroll int
+	running      bool
+	status       string
+	quit         bool
+}
+
+func newTUI(s *config.Schema, showHidden bool) *tui {
+	t := &tui{
+		schema:     s,
+		showHidden: showHidden,
+		expanded:   make(map[string]bool),
+		values:     make(map[string]map[string]string),
+	}
+	t.selectCursor()
+	return t
+}
+
+// nodes lists the visible commands of the tree, the root itself is
+// only listed when it has no subcommands
+func (t *tui) nodes() []*tuiNode {
+	var nodes []*tuiNode
+	var walk func(chain []*config.Command, depth int)
+	walk = func(chain []*config.Command, depth int) {
+		for _, cmd := range config.SortedCommands(chain[len(chain)-1].Commands) {
+			if cmd.Hidden && !t.showHidden {
+				continue
+			}
+			node := &tuiNode{chain: append(chain[:len(chain):len(chain)], cmd), depth: depth}
+			nodes = append(nodes, node)
+			if t.expanded[node.key()] {
+				walk(node.chain, depth+1)
+			}
+		}
+	}
+	walk([]*config.Command{t.schema}, 0)
+	if len(nodes) == 0 {
+		nodes = append(nodes, &tuiNode{chain: []*config.Command{t.schema}})
+	}
+	return nodes
+}
+
+// selectCursor shows the form of the command under the tree cursor,
+// commands with subcommands only expand like in the web sidebar
+func (t *tui) selectCursor() {
+	nodes := t.nodes()
+	if t.treeCursor >= len(nodes) {
+		t.treeCursor = len(nodes) - 1
+	}
+	node := nodes[t.treeCursor]
+	if len(node.cmd().Commands) > 0 {
+		return
+	}
+	if t.selected != nil && t.selected.key() == node.key() {
+		return
+	}
+	t.selected = node
+	t.fields = t.formFields(node.chain)
+	t.formCursor = 0
+	if t.values[node.key()] == nil {
+		values := make(map[string]string)
+		for _, f := range t.fields {
+			if f.typ != config.TypeBoolean {
+				values[f.name] = t.defaultOf(f)
+			}
+		}
+		t.values[node.key()] = values
+	}
+}
+
+func (t *tui) defaultOf(field *tuiField) string {
+	cmd := t.selected.cmd()
+	for _, arg := range cmd.Arguments {
+		if "arg-"+arg.Name == field.name {
+			return arg.Default
+		}
+	}
+	for _, opt := range append(cmd.Options[:len(cmd.Options):len(cmd.Options)], inheritedOptions(t.selected.chain)...) {
+		if opt.Spec().ID() == field.name {
+			return opt.Default
+		}
+	}
+	return ""
+}
+
+// formFields lists the inputs in the order of the web form
+func (t *tui) formFields(chain []*config.Command) []*tuiField {
+	cmd := chain[len(chain)-1]
+	var fields []*tuiField
+	for _, arg := range cmd.Arguments {
+		fields = append(fields, &tuiField{
+			section:     "Arguments",
+			label:       arg.Name,
+			description: arg.Description,
+			name:        "arg-" + arg.Name,
+			typ:         arg.Type,
+			choices:     arg.Choices,
+			secret:      arg.Secret,
+		})
+	}
+	addOptions := func(section string, options []*config.Option) {
+		for _, opt := range options {
+			spec := opt.Spec()
+			fields = append(fields, &tuiField{
+				section:     section,
+				label:       spec.Display(),
+				description: opt.Description,
+				name:        spec.ID(),
+				typ:         opt.Type,
+				choices:     opt.Choices,
+				secret:      opt.Secret,
+			})
+		}
+	}
+	for _, group := range groupOptions(cmd.Groups, visibleOptions(cmd.Options, t.showHidden)) {
+		section := "Options"
+		if group.Group != nil {
+			section = group.Group.Name
+		}
+		addOptions(section, group.Options)
+	}
+	addOptions("Global options", visibleOptions(inheritedOptions(chain), t.showHidden))
+	return fields
+}
+
+func (t *tui) handleKey(key string) tuiAction {
+	t.status = ""
+	switch key {
+	case "ctrl-r":
+		return actionRun
+	case "tab":
+		t.focus = (t.focus + 1) % 3
+		if t.focus == focusForm && t.selected == nil {
+			t.focus = focusOutput
+		}
+		return actionNone
+	case "shift-tab":
+		t.focus = (t.focus + 2) % 3
+		if t.focus == focusForm && t.selected == nil {
+			t.focus = focusTree
+		}
+		return actionNone
+	}
+	switch t.focus {
+	case focusTree:
+		t.handleTreeKey(key)
+	case focusForm:
+		return t.handleFormKey(key)
+	case focusOutput:
+		t.handleOutputKey(key)
+	}
+	return actionNone
+}
+
+func (t *tui) handleTreeKey(key string) {
+	nodes := t.nodes()
+	node := nodes[t.treeCursor]
+	switch key {
+	case "up":
+		if t.treeCursor > 0 {
+			t.treeCursor--
+		}
+	case "down":
+		if t.treeCursor < len(nodes)-1 {
+			t.treeCursor++
+		}
+	case "enter", "right":
+		if len(node.cmd().Commands) > 0 {
+			if key == "enter" || !t.expanded[node.key()] {
+				t.expanded[node.key()] = !t.expanded[node.key()]
+			}
+		} else if t.selected != nil {
+			t.focus = focusForm
+		}
+	case "left":
+		if t.expanded[node.key()] {
+			t.expanded[node.key()] = false
+			break
+		}
+		// move to the parent
+		for i := t.treeCursor - 1; i >= 0; i-- {
+			if nodes[i].depth < node.depth {
+				t.treeCursor = i
+				break
+			}
+		}
+	case "q":
+		t.quit = true
+	}
+	t.selectCursor()
+}
+
+func (t *tui) handleFormKey(key string) tuiAction {
+	if t.formCursor == len(t.fields) {
+		switch key {
+		case "enter", "space":
+			return actionRun
+		case "up":
+			t.moveFormCursor(-1)
+		}
+		return actionNone
+	}
+	field := t.fields[t.formCursor]
+	values := t.values[t.selected.key()]
+	value := values[field.name]
+	switch {
+	case key == "up":
+		t.moveFormCursor(-1)
+	case key == "down":
+		t.moveFormCursor(1)
+	case field.typ == config.TypeBoolean:
+		if key == "space" || key == "enter" {
+			if value == "on" {
+				values[field.name] = ""
+			} else {
+				values[field.name] = "on"
+			}
+		}
+	case len(field.choices) > 0:
+		step := 0
+		switch key {
+		case "right", "space":
+			step = 1
+		case "left":
+			step = -1
+		case "enter":
+			t.moveFormCursor(1)
+		}
+		if step != 0 {
+			cycle := field.cycleValues(t.defaultOf(field))
+			idx := 0
+			for i, v := range cycle {
+				if v == value {
+					idx = i
+				}
+			}
+			values[field.name] = cycle[(idx+step+len(cycle))%len(cycle)]
+		}
+	case key == "enter":
+		t.moveFormCursor(1)
+	case key == "backspace":
+		if runes := []rune(value); len(runes) > 0 {
+			values[field.name] = string(runes[:len(runes)-1])
+		}
+	case key == "space":
+		values[field.name] = value + " "
+	case len([]rune(key)) == 1:
+		values[field.name] = value + key
+	}
+	return actionNone
+}
+
+func (t *tui) moveFormCursor(delta int) {
+	t.formCursor += delta
+	if t.formCursor < 0 {
+		t.formCursor = 0
+	}
+	if t.formCursor > len(t.fields) {
+		t.formCursor = len(t.fields)
+	}
+}
+
+func (t *tui) handleOutputKey(key string) {
+	switch key {
+	case "up":
+		t.scrollOutput(1)
+	case "down":
+		t.scrollOutput(-1)
+	case "pgup":
+		t.scrollOutput(10)
+	case "pgdown":
+		t.scrollOutput(-10)
+	case "q":
+		t.quit = true
+	}
+}
+
+func (t *tui) scrollOutput(delta int) {
+	t.outputScroll += delta
+	if t.outputScroll > len(t.output)-1 {
+		t.outputScroll = len(t.output) - 1
+	}
+	if t.outputScroll < 0 {
+		t.outputScroll = 0
+	}
+}
+
+// prepareRun validates the form of the selected command and
+// builds its invocation, the same as a web form submission
+func (t *tui) prepareRun() (*invocation, error) {
+	if t.selected == nil {
+		return nil, fmt.Errorf("select a command first")
+	}
+	formData := make(map[string]string)
+	for k, v := range t.values[t.selected.key()] {
+		formData[k] = v
+	}
+	if err := validateForm(t.selected.chain, formData); err != nil {
+		return nil, err
+	}
+	return buildInvocation(t.selected.chain, formData), nil
+}
+
+// appendOutput adds a line to the output pane, control characters
+// are dropped so that they cannot break the layout
+func (t *tui) appendOutput(line string) {
+	line = strings.ReplaceAll(line, "\t", "    ")
+	line = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, line)
+	t.output = append(t.output, line)
+	if t.outputScroll > 0 {
+		// keep the scrolled position
+		t.outputScroll++
+	}
+}
+
+const (
+	ansiReverse = "\x1b[7m"
+	ansiBold    = "\x1b[1m"
+	ansiReset   = "\x1b[0m"
+)
+
+// render lays out the screen as width x height lines
+func (t *tui) render(width, height int) []string {
+	title := "cli2web tui"
+	if t.schema.Name != "" {
+		title = t.schema.Name + " - " + title
+	}
+	lines := []string{ansiReverse + pad(" "+title, width) + ansiReset}
+	bodyHeight := height - 2
+	if bodyHeight < 1 {
+		return lines
+	}
+
+	leftWidth := width / 3
+	if leftWidth > 32 {
+		leftWidth = 32
+	}
+	rightWidth := width - leftWidth - 1
+	left := t.renderTree(leftWidth, bodyHeight)
+
+	formHeight := bodyHeight / 2
+	right := t.renderForm(rightWidth, formHeight)
+	right = append(right, t.renderOutput(rightWidth, bodyHeight-formHeight)...)
+
+	for i := 0; i < bodyHeight; i++ {
+		lines = append(lines, left[i]+"│"+right[i])
+	}
+
+	status := t.status
+	if status == "" {
+		switch {
+		case t.running:
+			status = "running, ctrl-c to stop"
+		case t.focus == focusTree:
+			status = "enter: open  tab: form  q: quit"
+		case t.focus == focusForm:
+			status = "type to edit  space: toggle  ctrl-r: run  tab: output"
+		default:
+			status = "up/down: scroll  tab: commands  q: quit"
+		}
+	}
+	lines = append(lines, pad(" "+status, width))
+	return lines
+}
+
+func (t *tui) renderTree(width, height int) []string {
+	nodes := t.nodes()
+	var lines []string
+	for i, node := range nodes {
+		marker := "  "
+		if len(node.cmd().Commands) > 0 {
+			marker = "+ "
+			if t.expanded[node.key()] {
+				marker = "- "
+			}
+		}
+		name := node.cmd().Name
+		if name == "" {
+			name = "(root)"
+		}
+		line := pad(" "+strings.Repeat("  ", node.depth)+marker+name, width)
+		if i == t.treeCursor {
+			line = highlight(line, t.focus == focusTree)
+		}
+		lines = append(lines, line)
+	}
+	return window(lines, t.treeCursor, width, height)
+}
+
+func (t *tui) renderForm(width, height int) []string {
+	if t.selected == nil {
+		return window([]string{pad(" Select a command to begin.", width)}, 0, width, height)
+	}
+	cmd := t.selected.cmd()
+	name := t.schema.Name
+	if key := t.selected.key(); key != "" {
+		name = strings.TrimSpace(name + " " + key)
+	}
+	lines := []string{ansiBold + pad(" "+name, width) + ansiReset}
+	if cmd.Description != "" {
+		lines = append(lines, pad(" "+firstLine(cmd.Description), width))
+	}
+	if cmd.Deprecated != "" {
+		lines = append(lines, pad(" deprecated: "+cmd.Deprecated, width))
+	}
+	cursorLine := 0
+	values := t.values[t.selected.key()]
+	section := ""
+	for i, field := range t.fields {
+		if field.section != section {
+			section = field.section
+			lines = append(lines, pad(" "+section+":", width))
+		}
+		value := values[field.name]
+		var text string
+		switch {
+		case field.typ == config.TypeBoolean:
+			check := "[ ]"
+			if value == "on" {
+				check = "[x]"
+			}
+			text = check + " " + field.label
+		case len(field.choices) > 0:
+			text = field.label + ": < " + value + " >"
+		case field.secret:
+			text = field.label + ": " + strings.Repeat("*", len([]rune(value)))
+		default:
+			text = field.label + ": " + value
+		}
+		if field.description != "" {
+			text += "  (" + firstLine(field.description) + ")"
+		}
+		if i == t.formCursor {
+			cursorLine = len(lines)
+		}
+		line := pad("   "+text, width)
+		if i == t.formCursor {
+			line = highlight(line, t.focus == focusForm)
+		}
+		lines = append(lines, line)
+	}
+	run := pad("   [ Run ]", width)
+	if t.formCursor == len(t.fields) {
+		cursorLine = len(lines)
+		run = highlight(run, t.focus == focusForm)
+	}
+	lines = append(lines, run)
+	return window(lines, cursorLine, width, height)
+}
+
+func (t *tui) renderOutput(width, height int) []string {
+	header := "── Output "
+	if t.running {
+		header += "(running) "
+	}
+	header += strings.Repeat("─", max(width-len([]rune(header)), 0))
+	if t.focus == focusOutput {
+		header = ansiBold + header + ansiReset
+	}
+	lines := []string{pad(header, width)}
+	end := len(t.output) - t.outputScroll
+	start := end - (height - 1)
+	if start < 0 {
+		start = 0
+	}
+	for _, line := range t.output[start:end] {
+		lines = append(lines, pad(" "+line, width))
+	}
+	for len(lines) < height {
+		lines = append(lines, pad("", width))
+	}
+	return lines
+}
+
+// window keeps the cursor line visible within height lines,
+// padding with blank lines
+func window(lines []string, cursor int, width, height int) []string {
+	start := 0
+	if cursor >= height {
+		start = cursor - height + 1
+	}
+	lines = lines[start:]
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	for len(lines) < height {
+		lines = append(lines, pad("", width))
+	}
+	return lines
+}
+
+func highlight(line string, focused bool) string {
+	if focused {
+		return ansiReverse + line + ansiReset
+	}
+	return ansiBold + line + ansiReset
+}
+
+// pad truncates or pads s with spaces to width runes
+func pad(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) > width {
+		return string(runes[:width])
+	}
+	return s + strings.Repeat(" ", width-len(runes))
+}
+
+// parseKeys converts raw terminal input to key names, printable
+// characters are returned as themselves
+func parseKeys(data []byte) []string {
+	sequences := []struct {
+		seq string
+		key string
+	}{
+		{"\x1b[A", "up"},
+		{"\x1b[B", "down"},
+		{"\x1b[C", "right"},
+		{"\x1b[D", "left"},
+		{"\x1bOA", "up"},
+		{"\x1bOB", "down"},
+		{"\x1bOC", "right"},
+		{"\x1bOD", "left"},
+		{"\x1b[Z", "shift-tab"},
+		{"\x1b[5~", "pgup"},
+		{"\x1b[6~", "pgdown"},
+	}
+	var keys []string
+	s := string(data)
+	for len(s) > 0 {
+		matched := false
+		for _, seq := range sequences {
+			if strings.HasPrefix(s, seq.seq) {
+				keys = append(keys, seq.key)
+				s = s[len(seq.seq):]
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+		r := []rune(s)[0]
+		s = s[len(string(r)):]
+		switch r {
+		case '\r', '\n':
+			keys = append(keys, "enter")
+		case '\t':
+			keys = append(keys, "tab")
+		case ' ':
+			keys = append(keys, "space")
+		case 0x7f, 0x08:
+			keys = append(keys, "backspace")
+		case 0x03:
+			keys = append(keys, "ctrl-c")
+		case 0x12:
+			keys = append(keys, "ctrl-r")
+		case 0x1b:
+			keys = append(keys, "esc")
+		default:
+			if r >= 0x20 {
+				keys = append(keys, string(r))
+			}
+		}
+	}
+	return keys
+}
+
+// tuiEvent is sent by a running command, done is set when it exits
+type tuiEvent struct {
+	line string
+	done bool
+	err  error
+}
+
+// runTUI runs the event loop until quit, the terminal is put
+// in raw mode on the alternate screen
+func runTUI(t *tui) error {
+	stdin := int(os.Stdin.Fd())
+	state, err := term.MakeRaw(stdin)
+	if err != nil {
+		return fmt.Errorf("entering raw mode: %v", err)
+	}
+	defer term.Restore(stdin, state)
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer fmt.Print("\x1b[?25h\x1b[?1049l")
+
+	keys := make(chan []string)
+	go func() {
+		buf := make([]byte, 256)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			keys <- parseKeys(buf[:n])
+		}
+	}()
+
+	events := make(chan tuiEvent)
+	// redraw periodically to follow terminal resizes
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+
+	var running *exec.Cmd
+	for !t.quit {
+		width, height, err := term.GetSize(int(os.Stdout.Fd()))
+		if err != nil {
+			width, height = 80, 24
+		}
+		fmt.Print("\x1b[H" + strings.Join(t.render(width, height), "\r\n"))
+
+		select {
+		case ks, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			for _, key := range ks {
+				if key == "ctrl-c" {
+					if running != nil {
+						running.Process.Kill()
+					} else {
+						t.quit = true
+					}
+					continue
+				}
+				if t.handleKey(key) != actionRun || running != nil {
+					continue
+				}
+				inv, err := t.prepareRun()
+				if err != nil {
+					t.status = err.Error()
+					continue
+				}
+				running, err = startTUICommand(inv, events)
+				if err != nil {
+					t.status = err.Error()
+					continue
+				}
+				t.running = true
+				t.output = []string{"$ " + inv.String()}
+				t.outputScroll = 0
+			}
+		case ev := <-events:
+			if !ev.done {
+				t.appendOutput(ev.line)
+				continue
+			}
+			running = nil
+			t.running = false
+			if ev.err != nil {
+				t.appendOutput("[" + ev.err.Error() + "]")
+			} else {
+				t.appendOutput("[done]")
+			}
+		case <-ticker.C:
+		}
+	}
+	if running != nil {
+		running.Process.Kill()
+	}
+	return nil
+}
+
+// startTUICommand starts inv and sends its stdout and stderr line
+// by line, secrets masked, then a done event
+func startTUICommand(inv *invocation, events chan<- tuiEvent) (*exec.Cmd, error) {
+	cmd := inv.Command()
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	go func() {
+		var wg sync.WaitGroup
+		for _, r := range []io.Reader{stdout, stderr} {
+			wg.Add(1)
+			go func(r io.Reader) {
+				defer wg.Done()
+				scanner := bufio.NewScanner(r)
+				for scanner.Scan() {
+					events <- tuiEvent{line: inv.Mask(scanner.Text())}
+				}
+			}(r)
+		}
+		wg.Wait()
+		events <- tuiEvent{done: true, err: cmd.Wait()}
+	}()
+	return cmd, nil
+}
@@ -0,0 +1,127 @@
+package run
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/cli2web/config"
+)
+
+func tuiTestSchema() *config.Schema {
+	return &config.Schema{
+		Name: "kool",
+		Options: []*config.Option{
+			{Flags: "-v, --verbose", Type: "boolean", Persistent: true},
+		},
+		Commands: []*config.Command{
+			{
+				Name: "git",
+				Commands: []*config.Command{
+					{
+						Name:        "tag-next",
+						Description: "Tag the next version",
+						Arguments: []*config.Argument{
+							{Name: "kind", Choices: []string{"major", "minor"}},
+						},
+						Options: []*config.Option{
+							{Flags: "--jobs <n>", Type: "number", Default: "1"},
+							{Flags: "--token <token>", Type: "string", Secret: true},
+						},
+					},
+				},
+			},
+			{Name: "version"},
+			{Name: "debug", Lifecycle: config.Lifecycle{Hidden: true}},
+		},
+	}
+}
+
+func sendKeys(t *tui, keys ...string) tuiAction {
+	var action tuiAction
+	for _, key := range keys {
+		action = t.handleKey(key)
+	}
+	return action
+}
+
+func TestTUI_Run(t *testing.T) {
+	ui := newTUI(tuiTestSchema(), false)
+	// git is a group, it expands instead of opening a form
+	if ui.selected != nil {
+		t.Fatalf("expect no selection on a group, got %s", ui.selected.key())
+	}
+	sendKeys(ui, "enter", "down")
+	if ui.selected == nil || ui.selected.key() != "git tag-next" {
+		t.Fatalf("expect git tag-next selected")
+	}
+
+	// choose minor, clear the default jobs, type 4, set token, check --verbose
+	action := sendKeys(ui, "enter", "right", "right", "down", "backspace", "4", "down", "s", "3", "c", "down", "space", "down", "enter")
+	if action != actionRun {
+		t.Fatalf("expect enter on the run button to run")
+	}
+	inv, err := ui.prepareRun()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"kool", "git", "tag-next", "minor", "--jobs", "4", "--token", "s3c", "--verbose"}
+	if !reflect.DeepEqual(inv.Args, expected) {
+		t.Errorf("Args = %v, expected %v", inv.Args, expected)
+	}
+
+	// validated like the web form
+	sendKeys(ui, "up", "up", "up", "backspace", "x")
+	if _, err := ui.prepareRun(); err == nil || err.Error() != `option --jobs: expect a number, got "x"` {
+		t.Errorf("expect number validation error, got %v", err)
+	}
+}
+
+var ansiRegex = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+func TestTUI_Render(t *testing.T) {
+	ui := newTUI(tuiTestSchema(), false)
+	sendKeys(ui, "enter", "down", "enter", "right", "down", "down", "s", "3")
+	ui.appendOutput("hello\tworld\x07")
+
+	lines := ui.render(60, 24)
+	if len(lines) != 24 {
+		t.Fatalf("expect 24 lines, got %d", len(lines))
+	}
+	for i, line := range lines {
+		lines[i] = ansiRegex.ReplaceAllString(line, "")
+		if n := len([]rune(lines[i])); n != 60 {
+			t.Errorf("line %d has width %d: %q", i, n, lines[i])
+		}
+	}
+	screen := strings.Join(lines, "\n")
+	for _, s := range []string{
+		"kool - cli2web tui",
+		" - git",
+		"     tag-next",
+		"   version",
+		"kool git tag-next",
+		"kind: < major >",
+		"--jobs <n>: 1",
+		"--token <token>: **",
+		"[ ] -v, --verbose",
+		"[ Run ]",
+		"hello    world",
+	} {
+		if !strings.Contains(screen, s) {
+			t.Errorf("expect screen to contain %q, got:\n%s", s, screen)
+		}
+	}
+	if strings.Contains(screen, "debug") {
+		t.Errorf("expect hidden command left out:\n%s", screen)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	got := parseKeys([]byte("a \x1b[A\x1b[B\r\t\x1b[Z\x7f\x03\x12é"))
+	expected := []string{"a", "space", "up", "down", "enter", "tab", "shift-tab", "backspace", "ctrl-c", "ctrl-r", "é"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("parseKeys() = %v, expected %v", got, expected)
+	}
+}
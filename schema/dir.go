@@ -77,6 +77,10 @@ func (f *FSSchemaFile) Name() string {
 	return filepath.Base(f.path)
 }
 
+func (f *FSSchemaFile) Path() string {
+	return f.path
+}
+
 func (f *FSSchemaFile) Read() ([]byte, error) {
 	return os.ReadFile(f.path)
 }
@@ -141,6 +145,10 @@ func (f *EmbedSchemaFile) Name() string {
 	return filepath.Base(f.path)
 }
 
+func (f *EmbedSchemaFile) Path() string {
+	return f.path
+}
+
 func (f *EmbedSchemaFile) Read() ([]byte, error) {
 	return f.fs.ReadFile(f.path)
 }
@@ -205,6 +213,10 @@ func (f *GenericFSSchemaFile) Name() string {
 	return filepath.Base(f.path)
 }
 
+func (f *GenericFSSchemaFile) Path() string {
+	return f.path
+}
+
 func (f *GenericFSSchemaFile) Read() ([]byte, error) {
 	return fs.ReadFile(f.fs, f.path)
 }
@@ -1,9 +1,11 @@
 package schema
 
 import (
+	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"testing/fstest"
 
@@ -97,7 +99,7 @@ func TestParseSchemaFromFS_WithOSDirFS(t *testing.T) {
 		t.Fatalf("ParseSchemaFromFS with os.DirFS failed: %v", err)
 	}
 
-	// Verify the parsed schema (root name should be "root" for "." path)
+	// Verify the parsed schema
 	verifyTestSchema(t, schema)
 }
 
@@ -254,8 +256,6 @@ Show help information.
 {}
 ` + "```"),
 		},
-		// Note: git/git.md should now be found by the improved parsing logic
-		// that handles commands in subdirectories
 		"test-schema/git/git.md": &fstest.MapFile{
 			Data: []byte(`# Description
 
@@ -303,17 +303,23 @@ Show the working tree status.
 	}
 }
 
-// Helper function to verify the parsed schema structure
+// Helper function to verify the parsed schema structure, a tree in
+// the layout without index files: help.md is the root command
 func verifyTestSchema(t *testing.T, schema *config.Schema) {
 	if schema == nil {
 		t.Fatal("Schema is nil")
 	}
 
-	// Check root name - with new logic, single .md file determines root name
-	// In createTestSchemaStructure, we have help.md with empty settings {}, so command name is "help"
-	actualExpectedRoot := "help"
-	if schema.Name != actualExpectedRoot {
-		t.Errorf("Expected root name '%s', got '%s'", actualExpectedRoot, schema.Name)
+	// Check root name - without _index.md, README.md or <dir>.md,
+	// the only .md file of the root names the root command
+	if schema.Name != "help" {
+		t.Errorf("Expected root name 'help', got '%s'", schema.Name)
+	}
+	if schema.Description != "Show help information." {
+		t.Errorf("Root command description mismatch: %s", schema.Description)
+	}
+	if len(schema.Arguments) != 1 {
+		t.Errorf("Expected 1 argument for root command, got %d", len(schema.Arguments))
 	}
 
 	// Debug: Print actual commands found
@@ -322,36 +328,11 @@ func verifyTestSchema(t *testing.T, schema *config.Schema) {
 		t.Logf("  Command %d: %s (%s)", i, cmd.Name, cmd.Description)
 	}
 
-	// Should have 2 commands: git and help
-	// git/git.md should now be found with the improved parsing logic
-	if len(schema.Commands) != 2 {
-		t.Fatalf("Expected 2 commands (help and git), got %d", len(schema.Commands))
-	}
-
-	// Find and verify each command
-	var gitCmd, helpCmd *config.Command
-	for _, cmd := range schema.Commands {
-		switch cmd.Name {
-		case "git":
-			gitCmd = cmd
-		case "help":
-			helpCmd = cmd
-		}
-	}
-
-	// Verify help command
-	if helpCmd == nil {
-		t.Fatal("Help command not found")
-	}
-	if helpCmd.Description != "Show help information." {
-		t.Errorf("Help command description mismatch: %s", helpCmd.Description)
-	}
-	if len(helpCmd.Arguments) != 1 {
-		t.Errorf("Expected 1 argument for help command, got %d", len(helpCmd.Arguments))
-	}
-	if len(helpCmd.Commands) != 0 {
-		t.Errorf("Expected 0 subcommands for help command, got %d", len(helpCmd.Commands))
+	// Should have 1 command: git
+	if len(schema.Commands) != 1 {
+		t.Fatalf("Expected 1 command (git), got %d", len(schema.Commands))
 	}
+	gitCmd := schema.Commands[0]
 
 	// Verify git command
 	if gitCmd == nil {
@@ -481,11 +462,10 @@ func verifyCorrectSchema(t *testing.T, schema *config.Schema, expectedRoot strin
 		t.Fatal("Schema is nil")
 	}
 
-	// Check root name - with new logic, multiple .md files: git.md and help.md
-	// Should select first alphabetically: git.md, so root name is "git"
-	actualExpectedRoot := "git"
-	if schema.Name != actualExpectedRoot {
-		t.Errorf("Expected root name '%s', got '%s'", actualExpectedRoot, schema.Name)
+	// Check root name - git.md and help.md are commands, git.md
+	// describes the directory git, so the root is named after the directory
+	if schema.Name != expectedRoot {
+		t.Errorf("Expected root name '%s', got '%s'", expectedRoot, schema.Name)
 	}
 
 	// Debug: Print actual commands found
@@ -549,3 +529,119 @@ func verifyCorrectSchema(t *testing.T, schema *config.Schema, expectedRoot strin
 		t.Errorf("Expected 0 subcommands for help command, got %d", len(helpCmd.Commands))
 	}
 }
+
+func TestParseSchemaFromFS_IndexFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"kool/README.md":         {Data: []byte("# Description\nKool readme\n")},
+		"kool/_index.md":         {Data: []byte("# Description\nKool\n")},
+		"kool/kool.md":           {Data: []byte("# Description\nignored\n")},
+		"kool/version.md":        {Data: []byte("# Description\nPrint the version\n")},
+		"kool/notes.txt":         {Data: []byte("not a command\n")},
+		"kool/git.md":            {Data: []byte("# Description\nGit\n")},
+		"kool/git/status.md":     {Data: []byte("# Description\nStatus\n")},
+		"kool/git/tag.md":        {Data: []byte("# Settings\n```json\n{\"name\": \"tag-next\"}\n```\n")},
+		"kool/go/README.md":      {Data: []byte("# Description\nignored\n")},
+		"kool/go/go.md":          {Data: []byte("# Description\nGo\n")},
+		"kool/go/mod/mod.md":     {Data: []byte("# Description\nMod\n")},
+		"kool/go/mod/replace.md": {Data: []byte("# Description\nReplace\n")},
+	}
+	s, err := ParseSchemaFromFS(fsys, "kool")
+	if err != nil {
+		t.Fatalf("ParseSchemaFromFS failed: %v", err)
+	}
+
+	var outline []string
+	var walk func(commands []*config.Command, indent string)
+	walk = func(commands []*config.Command, indent string) {
+		for _, cmd := range commands {
+			outline = append(outline, indent+cmd.Name+": "+cmd.Description)
+			walk(cmd.Commands, indent+"  ")
+		}
+	}
+	walk([]*config.Command{s}, "")
+	expected := []string{
+		"kool: Kool",
+		"  git: Git",
+		"    status: Status",
+		"    tag-next: ",
+		"  go: Go",
+		"    mod: Mod",
+		"      replace: Replace",
+		"  version: Print the version",
+	}
+	if got := strings.Join(outline, "\n"); got != strings.Join(expected, "\n") {
+		t.Errorf("commands:\n%s\nexpected:\n%s", got, strings.Join(expected, "\n"))
+	}
+
+	// a file next to a directory with an index is ambiguous
+	fsys["kool/git/_index.md"] = &fstest.MapFile{Data: []byte("# Description\nGit\n")}
+	_, err = ParseSchemaFromFS(fsys, "kool")
+	if err == nil || !strings.Contains(err.Error(), "command git is described by both git.md and git/_index.md") {
+		t.Errorf("expect ambiguous command error, got %v", err)
+	}
+}
+
+func TestParseSchemaFromFS_OldLayout(t *testing.T) {
+	fsys := fstest.MapFS{
+		"kool.md":          {Data: []byte("# Description\nKool utilities\n")},
+		"git/git.md":       {Data: []byte("# Description\nGit\n")},
+		"git/README.md":    {Data: []byte("# Notes\nRun git help first.\n")},
+		"git/tag/tag.md":   {Data: []byte("# Description\nTag\n")},
+		"go/README.md":     {Data: []byte("# Description\nGo\n")},
+		"go/mod/mod.md":    {Data: []byte("# Description\nMod\n")},
+		"go/mod/notes.txt": {Data: []byte("not a command\n")},
+	}
+	s, err := ParseSchemaFromFS(fsys, ".")
+	if err != nil {
+		t.Fatalf("ParseSchemaFromFS failed: %v", err)
+	}
+
+	var outline []string
+	var walk func(commands []*config.Command, indent string)
+	walk = func(commands []*config.Command, indent string) {
+		for _, cmd := range commands {
+			outline = append(outline, indent+cmd.Name+": "+cmd.Description)
+			walk(cmd.Commands, indent+"  ")
+		}
+	}
+	walk([]*config.Command{s}, "")
+	expected := []string{
+		"kool: Kool utilities",
+		"  git: Git",
+		"    tag: Tag",
+		"  go: Go",
+		"    mod: Mod",
+	}
+	if got := strings.Join(outline, "\n"); got != strings.Join(expected, "\n") {
+		t.Errorf("commands:\n%s\nexpected:\n%s", got, strings.Join(expected, "\n"))
+	}
+}
+
+func TestParseSchemaFromFS_Order(t *testing.T) {
+	fsys := fstest.MapFS{
+		"kool/_index.md":          {Data: []byte("# Description\nKool\n")},
+		"kool/help.md":            {Data: []byte("---\nweight: 10\n---\n# Description\nHelp\n")},
+		"kool/version.md":         {Data: []byte("# Settings\n```json\n{\"order\": 5}\n```\n")},
+		"kool/10-zz.md":           {Data: []byte("# Description\nZz\n")},
+		"kool/2-go/go.md":         {Data: []byte("# Description\nGo\n")},
+		"kool/01-git/_index.md":   {Data: []byte("# Description\nGit\n")},
+		"kool/01-git/02-tag.md":   {Data: []byte("# Description\nTag\n")},
+		"kool/01-git/1-status.md": {Data: []byte("# Description\nStatus\n")},
+	}
+	s, err := ParseSchemaFromFS(fsys, "kool")
+	if err != nil {
+		t.Fatalf("ParseSchemaFromFS failed: %v", err)
+	}
+	var got []string
+	for _, cmd := range s.Commands {
+		got = append(got, fmt.Sprintf("%s(%d)", cmd.Name, cmd.Order))
+	}
+	// prefixed first by number, order settings apply where commands are listed
+	expected := "git(0) go(0) zz(0) help(10) version(5)"
+	if strings.Join(got, " ") != expected {
+		t.Errorf("commands = %s, expected %s", strings.Join(got, " "), expected)
+	}
+	if git := s.Commands[0]; len(git.Commands) != 2 || git.Commands[0].Name != "status" || git.Commands[1].Name != "tag" {
+		t.Errorf("unexpected git subcommands %+v", git.Commands)
+	}
+}
@@ -0,0 +1,167 @@
+package schema
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/xhd2015/cli2web/config"
+	"github.com/xhd2015/cli2web/markjson"
+)
+
+// WriteSchemaDir writes s into dir using the markdown layout read by
+// ParseSchemaFromDir: dir/_index.md for the root command,
+// dir/<name>/<name>.md for each command with subcommands and
+// dir/<name>.md for each leaf command. Sibling commands get an order
+// prefix, like 01-<name>, when file name order would change theirs.
+// A leaf command named like an index file, e.g. README, gets a
+// directory so it is not read as the command of dir.
+func WriteSchemaDir(s *config.Schema, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := writeCommandFile(s, filepath.Join(dir, indexFiles[0])); err != nil {
+		return err
+	}
+	return writeCommandDirs(s.Commands, dir)
+}
+
+func writeCommandDirs(commands []*config.Command, dir string) error {
+	prefixed := needsOrderPrefix(commands)
+	width := len(strconv.Itoa(len(commands)))
+	if width < 2 {
+		width = 2
+	}
+	reserved := indexCandidates(filepath.Base(dir))
+	for i, cmd := range commands {
+		if cmd.Name == "" {
+			return fmt.Errorf("command without name in %s", dir)
+		}
+		base := cmd.Name
+		if prefixed {
+			base = fmt.Sprintf("%0*d-%s", width, i+1, cmd.Name)
+		}
+		if len(cmd.Commands) == 0 && indexOfString(reserved, base+".md") < 0 {
+			if err := writeCommandFile(cmd, filepath.Join(dir, base+".md")); err != nil {
+				return err
+			}
+			continue
+		}
+		cmdDir := filepath.Join(dir, base)
+		if err := os.MkdirAll(cmdDir, 0755); err != nil {
+			return err
+		}
+		if err := writeCommandFile(cmd, filepath.Join(cmdDir, cmd.Name+".md")); err != nil {
+			return err
+		}
+		if err := writeCommandDirs(cmd.Commands, cmdDir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// needsOrderPrefix tells if the files of commands need an order
+// prefix to be read back in the same order, see sortEntries
+func needsOrderPrefix(commands []*config.Command) bool {
+	for i, cmd := range commands {
+		if orderPrefixRegex.MatchString(cmd.Name) {
+			return true
+		}
+		if i > 0 && commands[i-1].Name >= cmd.Name {
+			return true
+		}
+	}
+	return false
+}
+
+func writeCommandFile(cmd *config.Command, file string) error {
+	content, err := RenderCommandMarkdown(cmd)
+	if err != nil {
+		return fmt.Errorf("rendering %s: %w", cmd.Name, err)
+	}
+	return os.WriteFile(file, []byte(content), 0644)
+}
+
+// RenderCommandMarkdown renders a single command, without its subcommands,
+// as markdown sections understood by the directory schema parser.
+// Fields without a section of their own go to # Settings, so that
+// parsing the result gives back the same command.
+func RenderCommandMarkdown(cmd *config.Command) (string, error) {
+	var sb strings.Builder
+	settings := settingsOf(cmd)
+	if fitsDescriptionSection(cmd.Description) {
+		sb.WriteString("# Description\n\n")
+		sb.WriteString(cmd.Description)
+		sb.WriteString("\n\n")
+	} else {
+		// the description section collapses whitespace
+		settings.Description = cmd.Description
+	}
+	if len(cmd.Options) > 0 {
+		if err := writeJSONSection(&sb, "Options", cmd.Options); err != nil {
+			return "", err
+		}
+	}
+	if len(cmd.Arguments) > 0 {
+		if err := writeJSONSection(&sb, "Arguments", cmd.Arguments); err != nil {
+			return "", err
+		}
+	}
+	if len(cmd.Examples) > 0 {
+		if section, ok := examplesSection(cmd.Examples); ok {
+			sb.WriteString(section)
+			sb.WriteString("\n")
+		} else {
+			// e.g. a description line starting with "#"
+			settings.Examples = cmd.Examples
+		}
+	}
+	if err := writeJSONSection(&sb, "Settings", settings); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
+
+// examplesSection renders examples as a # Examples section, each
+// usage in a fence no line of it closes. ok is false if the section
+// would not read back the same.
+func examplesSection(examples []*config.Example) (section string, ok bool) {
+	s := &markjson.Section{Title: "Examples", Level: 1}
+	for _, ex := range examples {
+		if ex.Description != "" {
+			s.Snippets = append(s.Snippets, &markjson.Snippet{Type: markjson.Text, Content: ex.Description})
+		}
+		// an empty block ends an example without usage,
+		// else its description is read as the next one's
+		s.Snippets = append(s.Snippets, &markjson.Snippet{Type: markjson.Code, Language: "sh", Content: ex.Usage})
+	}
+	section = markjson.Sections{s}.Render()
+	parsed, err := markjson.Parse(section)
+	if err != nil || len(parsed) != 1 || !reflect.DeepEqual(parseExamples(parsed[0].Snippets), examples) {
+		return "", false
+	}
+	return section, true
+}
+
+// fitsDescriptionSection tells if desc reads back the same
+// from a # Description section
+func fitsDescriptionSection(desc string) bool {
+	return desc != "" && cleanupDescription(desc) == desc &&
+		!strings.HasPrefix(desc, "#") && !strings.Contains(desc, "```")
+}
+
+func writeJSONSection(sb *strings.Builder, title string, v interface{}) error {
+	data, err := compactJSON(v)
+	if err != nil {
+		return err
+	}
+	sb.WriteString("# " + title + "\n")
+	sb.WriteString("```json\n")
+	sb.Write(data)
+	sb.WriteString("\n```\n\n")
+	return nil
+}
@@ -0,0 +1,149 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/xhd2015/cli2web/config"
+)
+
+func TestWriteSchemaDir(t *testing.T) {
+	s := &config.Schema{
+		Name: "kool",
+		Commands: []*config.Command{
+			{
+				Name:        "git",
+				Description: "Git commands",
+				Commands: []*config.Command{
+					{
+						Name:        "tag-next",
+						Description: "Get the next git tag",
+						Options: []*config.Option{
+							{Flags: "-p, --push", Type: config.TypeBoolean, Description: "push the tag"},
+							{Flags: "--format <fmt>", Type: config.TypeString, Default: "text"},
+						},
+						Arguments: []*config.Argument{
+							{Name: "dir", Type: config.TypeString},
+						},
+						Examples: []*config.Example{
+							{Usage: "kool git tag-next --push", Description: "Tag and push"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	dir := t.TempDir()
+	if err := WriteSchemaDir(s, dir); err != nil {
+		t.Fatalf("WriteSchemaDir() error = %v", err)
+	}
+	parsed, err := ParseSchemaFromDir(dir)
+	if err != nil {
+		t.Fatalf("ParseSchemaFromDir() error = %v", err)
+	}
+
+	if parsed.Name != "kool" {
+		t.Errorf("Name = %q, expected kool", parsed.Name)
+	}
+	if len(parsed.Commands) != 1 || len(parsed.Commands[0].Commands) != 1 {
+		t.Fatalf("Expected git with one subcommand, got %+v", parsed.Commands)
+	}
+	tagNext := parsed.Commands[0].Commands[0]
+	if tagNext.Name != "tag-next" || tagNext.Description != "Get the next git tag" {
+		t.Errorf("Unexpected command %s: %s", tagNext.Name, tagNext.Description)
+	}
+	if len(tagNext.Options) != 2 || tagNext.Options[0].Flags != "-p, --push" || tagNext.Options[1].Default != "text" {
+		t.Errorf("Unexpected options %+v", tagNext.Options)
+	}
+	if len(tagNext.Arguments) != 1 || tagNext.Arguments[0].Name != "dir" {
+		t.Errorf("Unexpected arguments %+v", tagNext.Arguments)
+	}
+	if len(tagNext.Examples) != 1 || tagNext.Examples[0].Usage != "kool git tag-next --push" || tagNext.Examples[0].Description != "Tag and push" {
+		t.Errorf("Unexpected examples %+v", tagNext.Examples)
+	}
+}
+
+func TestWriteSchemaDir_RoundTrip(t *testing.T) {
+	// siblings are not in name order, leaves are named like index files
+	s := &config.Schema{
+		Name:        "kool",
+		Description: "Kool utilities",
+		Options: []*config.Option{
+			{Flags: "-c, --config <file>", Type: config.TypeString, Persistent: true, Secret: true, Env: "KOOL_CONFIG"},
+		},
+		PersistentPlacement: config.PlacementBefore,
+		Commands: []*config.Command{
+			{
+				Name:        "git",
+				Aliases:     []string{"g"},
+				Description: "Git commands.\n\nMultiple  paragraphs are kept.",
+				Groups: []*config.OptionGroup{
+					{Name: "Advanced", Order: 1, Collapsed: true},
+				},
+				Options: []*config.Option{
+					{Flags: "--depth <n>", Type: config.TypeNumber, Default: "1", Group: "Advanced"},
+					{Flags: "--mode <mode>", Type: config.TypeString, Choices: []string{"fast", "safe"}},
+				},
+				Commands: []*config.Command{
+					{
+						Name:        "tag-next",
+						Description: "# Get the next git tag",
+						Arguments: []*config.Argument{
+							{Name: "dir", Type: config.TypeString, Default: "."},
+						},
+						Examples: []*config.Example{
+							{Description: "Run in the repository root"},
+							{Usage: "kool git tag-next", Description: "Print the tag"},
+							{Usage: "kool git tag-next \\\n  --push"},
+							// a fence line in the usage gets a longer fence
+							{Usage: "kool git tag-next <<EOF\n```\nEOF", Description: "Fenced notes"},
+						},
+						Output:    &config.Output{Type: "text", Description: "The next tag"},
+						Lifecycle: config.Lifecycle{Experimental: true},
+					},
+					{
+						Name:      "next-tag",
+						Lifecycle: config.Lifecycle{Hidden: true, Deprecated: "renamed", Replacement: "git tag-next"},
+					},
+					{Name: "git"},
+				},
+			},
+			{
+				Name:        "README",
+				Description: "Read me",
+				// read as a header in a # Examples section, kept in settings
+				Examples: []*config.Example{{Description: "# Print it\nto stdout", Usage: "kool README"}},
+			},
+			{
+				Name: "docs",
+				// in name order, so written without order prefix
+				Commands: []*config.Command{
+					{Name: "README", Description: "Docs read me"},
+					{Name: "_index"},
+					{Name: "docs"},
+				},
+			},
+			{Name: "help", Order: 1},
+		},
+	}
+
+	dir := t.TempDir()
+	if err := WriteSchemaDir(s, dir); err != nil {
+		t.Fatalf("WriteSchemaDir() error = %v", err)
+	}
+	parsed, err := ParseSchemaFromDir(dir)
+	if err != nil {
+		t.Fatalf("ParseSchemaFromDir() error = %v", err)
+	}
+	expected, err := compactJSON(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := compactJSON(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(actual) != string(expected) {
+		t.Errorf("Round trip =\n%s\nexpected\n%s", actual, expected)
+	}
+}
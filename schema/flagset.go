@@ -0,0 +1,63 @@
+package schema
+
+import (
+	"flag"
+	"time"
+
+	"github.com/xhd2015/cli2web/config"
+)
+
+// boolFlag is implemented by flag.Value types that take no argument
+type boolFlag interface {
+	IsBoolFlag() bool
+}
+
+// FromFlagSet converts the flags defined in fs to the options of a
+// command named name. Flag types are detected from the flag.Value:
+// bool flags become checkboxes, int, uint and float flags numbers,
+// durations and custom values strings.
+func FromFlagSet(name string, fs *flag.FlagSet) *config.Command {
+	cmd := &config.Command{Name: name}
+	fs.VisitAll(func(f *flag.Flag) {
+		cmd.Options = append(cmd.Options, convertGoFlag(f))
+	})
+	return cmd
+}
+
+func convertGoFlag(f *flag.Flag) *config.Option {
+	placeholder, usage := flag.UnquoteUsage(f)
+	opt := &config.Option{
+		Flags:       "-" + f.Name,
+		Description: usage,
+		Type:        goFlagType(f.Value),
+		Default:     f.DefValue,
+	}
+	if opt.Type == config.TypeBoolean {
+		if opt.Default == "false" {
+			opt.Default = ""
+		}
+		return opt
+	}
+	if placeholder == "" {
+		placeholder = "value"
+	}
+	opt.Flags += " <" + placeholder + ">"
+	return opt
+}
+
+func goFlagType(value flag.Value) string {
+	if b, ok := value.(boolFlag); ok && b.IsBoolFlag() {
+		return config.TypeBoolean
+	}
+	getter, ok := value.(flag.Getter)
+	if !ok {
+		return config.TypeString
+	}
+	switch getter.Get().(type) {
+	case time.Duration:
+		return config.TypeString
+	case int, int64, uint, uint64, float64:
+		return config.TypeNumber
+	}
+	return config.TypeString
+}
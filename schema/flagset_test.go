@@ -0,0 +1,45 @@
+package schema
+
+import (
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xhd2015/cli2web/config"
+)
+
+type levelValue string
+
+func (l *levelValue) String() string     { return string(*l) }
+func (l *levelValue) Set(s string) error { *l = levelValue(s); return nil }
+
+func TestFromFlagSet(t *testing.T) {
+	fs := flag.NewFlagSet("kool", flag.ContinueOnError)
+	fs.Bool("v", false, "verbose output")
+	fs.Int("count", 3, "number of runs")
+	fs.Duration("timeout", time.Second, "how long to wait")
+	fs.String("name", "", "the `user` to greet")
+	level := levelValue("info")
+	fs.Var(&level, "level", "log level")
+
+	cmd := FromFlagSet("kool", fs)
+	if cmd.Name != "kool" {
+		t.Errorf("Name = %q, expected kool", cmd.Name)
+	}
+	var actual []string
+	for _, opt := range cmd.Options {
+		actual = append(actual, strings.Join([]string{opt.Flags, opt.Type, opt.Default, opt.Description}, "|"))
+	}
+	// sorted by name, the same as flag.VisitAll
+	expected := []string{
+		"-count <int>|" + config.TypeNumber + "|3|number of runs",
+		"-level <value>|" + config.TypeString + "|info|log level",
+		"-name <user>|" + config.TypeString + "||the user to greet",
+		"-timeout <duration>|" + config.TypeString + "|1s|how long to wait",
+		"-v|" + config.TypeBoolean + "||verbose output",
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Options =\n  %s\nexpected\n  %s", strings.Join(actual, "\n  "), strings.Join(expected, "\n  "))
+	}
+}
@@ -0,0 +1,146 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/xhd2015/cli2web/config"
+	"github.com/xhd2015/cli2web/markjson"
+)
+
+// FormatSchemaDir formats the markdown files read by ParseSchemaFromDir
+// under dir, see FormatMarkdown. It returns the files that were not
+// formatted, relative to dir, and rewrites them if write is set.
+func FormatSchemaDir(dir string, write bool) ([]string, error) {
+	sources := make(sourceMap)
+	if _, err := parseSchema(NewFSSchemaDir(dir), sources); err != nil {
+		return nil, err
+	}
+	contents := make(map[string]string)
+	for _, source := range sources {
+		if strings.HasSuffix(source.file, ".md") {
+			contents[source.file] = source.content
+		}
+	}
+	files := make([]string, 0, len(contents))
+	for file := range contents {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	var changed []string
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		formatted, err := FormatMarkdown(path, contents[file])
+		if err != nil {
+			return nil, err
+		}
+		if formatted == contents[file] {
+			continue
+		}
+		changed = append(changed, file)
+		if write {
+			if err := os.WriteFile(path, []byte(formatted), 0644); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return changed, nil
+}
+
+// FormatMarkdown formats a command markdown file: titles of command
+// sections are cased like "Options", JSON blocks of options, arguments
+// and settings are reindented, options are sorted by name and tables
+// of options and arguments are aligned. Other content is kept as
+// written. file names the content in errors.
+func FormatMarkdown(file string, content string) (string, error) {
+	doc, err := markjson.ParseDocument(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse markdown content: %w", err)
+	}
+	for _, section := range doc.Sections {
+		title := strings.ToLower(section.Title)
+		if !isCommandFieldSection(title) {
+			continue
+		}
+		section.Title = strings.ToUpper(title[:1]) + title[1:]
+		if title == "description" || title == "examples" {
+			continue
+		}
+		for _, snippet := range section.Snippets {
+			switch {
+			case snippet.Type == markjson.Code && FormatOfLanguage(snippet.Language) == FormatJSON:
+				if err := formatJSONSnippet(file, snippet, title == "options"); err != nil {
+					return "", fmt.Errorf("failed to format %s: %w", section.Title, err)
+				}
+			case snippet.Type == markjson.Table && title != "settings":
+				if title == "options" {
+					sortOptionRows(snippet)
+				}
+				// rendered from the rows
+				snippet.Content = ""
+			}
+		}
+	}
+	return doc.Render(), nil
+}
+
+// formatJSONSnippet reindents a JSON snippet the way WriteSchemaDir
+// writes it, keeping zero values
+func formatJSONSnippet(file string, snippet *markjson.Snippet, sortOptions bool) error {
+	var v interface{}
+	if err := unmarshalSnippet(file, snippet, &v); err != nil {
+		return err
+	}
+	dec := json.NewDecoder(strings.NewReader(snippet.Content))
+	dec.UseNumber()
+	value, err := decodeOrdered(dec, false)
+	if err != nil {
+		return err
+	}
+	if list, ok := value.([]interface{}); ok && sortOptions {
+		sort.SliceStable(list, func(i, j int) bool {
+			return optionSortKey(list[i]) < optionSortKey(list[j])
+		})
+	}
+	var buf strings.Builder
+	writeOrdered(&buf, value, "")
+	snippet.Content = buf.String()
+	return nil
+}
+
+func optionSortKey(option interface{}) string {
+	obj, ok := option.(*orderedObject)
+	if !ok {
+		return ""
+	}
+	for i, key := range obj.keys {
+		if flags, ok := obj.values[i].(string); ok && key == "flags" {
+			return flagSortKey(flags)
+		}
+	}
+	return ""
+}
+
+// flagSortKey sorts options by their name, without dashes
+// and ignoring case
+func flagSortKey(flags string) string {
+	return strings.ToLower(strings.TrimLeft(config.ParseFlags(flags).Name, "-"))
+}
+
+// sortOptionRows sorts the rows of an options table by flags
+func sortOptionRows(table *markjson.Snippet) {
+	for _, column := range table.Columns {
+		if normalizeColumn(column) != "flags" {
+			continue
+		}
+		sort.SliceStable(table.Rows, func(i, j int) bool {
+			return flagSortKey(unquoteCell(table.Rows[i][column])) < flagSortKey(unquoteCell(table.Rows[j][column]))
+		})
+		return
+	}
+}
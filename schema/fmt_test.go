@@ -0,0 +1,142 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFormatMarkdown(t *testing.T) {
+	content := `---
+name: tag-next # front matter is kept
+---
+Notes before the first header are kept.
+
+# DESCRIPTION
+
+Tag the next version
+
+# options
+` + "```json" + `
+[{"flags": "--push", "type": "boolean", "default": ""},
+ {"flags": "-a, --annotate", "description": "annotate the tag"}]
+` + "```" + `
+
+# Arguments
+| name | description |
+|--|--|
+| kind | major \| minor |
+
+# Examples
+` + "```sh" + `
+# indentation of examples is kept
+  kool git tag-next
+` + "```"
+
+	expected := `---
+name: tag-next # front matter is kept
+---
+Notes before the first header are kept.
+# Description
+Tag the next version
+
+# Options
+` + "```json" + `
+[
+    {
+        "flags": "-a, --annotate",
+        "description": "annotate the tag"
+    },
+    {
+        "flags": "--push",
+        "type": "boolean",
+        "default": ""
+    }
+]
+` + "```" + `
+
+# Arguments
+| name | description    |
+| ---- | -------------- |
+| kind | major \| minor |
+
+# Examples
+` + "```sh" + `
+# indentation of examples is kept
+  kool git tag-next
+` + "```" + `
+`
+	formatted, err := FormatMarkdown("tag.md", content)
+	if err != nil {
+		t.Fatalf("FormatMarkdown() error = %v", err)
+	}
+	if formatted != expected {
+		t.Errorf("FormatMarkdown() =\n%s\nexpected:\n%s", formatted, expected)
+	}
+	again, err := FormatMarkdown("tag.md", formatted)
+	if err != nil {
+		t.Fatalf("FormatMarkdown() error = %v", err)
+	}
+	if again != formatted {
+		t.Errorf("FormatMarkdown() is not stable:\n%s", again)
+	}
+
+	before, err := parseCommandFromMarkdown(&MockSchemaFile{name: "tag.md", content: content}, "tag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := parseCommandFromMarkdown(&MockSchemaFile{name: "tag.md", content: formatted}, "tag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	before.Options[0], before.Options[1] = before.Options[1], before.Options[0]
+	if !reflect.DeepEqual(before, after) {
+		t.Errorf("formatting changed the command:\n%+v\n%+v", before, after)
+	}
+
+	_, err = FormatMarkdown("tag.md", "# Options\n```json\n[{]\n```\n")
+	if err == nil || err.Error() != "failed to format Options: tag.md:3:3: invalid character ']' looking for beginning of object key string" {
+		t.Errorf("expect located error, got %v", err)
+	}
+}
+
+func TestFormatSchemaDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"_index.md":     "# description\nKool\n",
+		"git/git.md":    "# Description\nGit\n",
+		"git/notes.txt": "# not a schema file\n",
+	}
+	for file, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	changed, err := FormatSchemaDir(dir, false)
+	if err != nil {
+		t.Fatalf("FormatSchemaDir() error = %v", err)
+	}
+	if !reflect.DeepEqual(changed, []string{"_index.md"}) {
+		t.Errorf("FormatSchemaDir() = %v, expected [_index.md]", changed)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "_index.md")); string(data) != files["_index.md"] {
+		t.Errorf("expect check mode to leave files as is, got %q", data)
+	}
+
+	if _, err := FormatSchemaDir(dir, true); err != nil {
+		t.Fatalf("FormatSchemaDir() error = %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "_index.md")); string(data) != "# Description\nKool\n" {
+		t.Errorf("unexpected formatted _index.md %q", data)
+	}
+	changed, err = FormatSchemaDir(dir, false)
+	if err != nil || len(changed) != 0 {
+		t.Errorf("expect all files formatted, got %v %v", changed, err)
+	}
+}
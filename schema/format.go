@@ -0,0 +1,242 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Schema file formats
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+var tomlLineRegex = regexp.MustCompile(`^(\[\[?[\w.-]+\]\]?|[\w.-]+\s*=)`)
+
+// DetectFormat returns the format of a schema by the extension
+// of file, or by content when file is empty or has no known extension,
+// e.g. when read from stdin
+func DetectFormat(file string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json":
+		return FormatJSON
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "{") || strings.HasPrefix(line, "[") && !tomlLineRegex.MatchString(line) {
+			return FormatJSON
+		}
+		if tomlLineRegex.MatchString(line) {
+			return FormatTOML
+		}
+		return FormatYAML
+	}
+	return FormatJSON
+}
+
+// FormatOfLanguage maps a markdown code block language to a format,
+// returns "" for languages that are not data formats
+func FormatOfLanguage(language string) string {
+	switch strings.ToLower(language) {
+	case "json":
+		return FormatJSON
+	case "yaml", "yml":
+		return FormatYAML
+	case "toml":
+		return FormatTOML
+	}
+	return ""
+}
+
+// Unmarshal decodes data of the given format into v, using v's json tags
+// for YAML and TOML as well. Scalars are converted to strings where v
+// expects one, so `default: 3` works in YAML.
+func Unmarshal(data []byte, format string, v interface{}) error {
+	if format == FormatJSON || format == "" {
+		return json.Unmarshal(data, v)
+	}
+	generic, err := decodeGeneric(data, format)
+	if err != nil {
+		return err
+	}
+	if generic == nil {
+		// empty document
+		return nil
+	}
+	return unmarshalGeneric(generic, v)
+}
+
+// unmarshalGeneric decodes maps, slices and scalars into v
+// the same way as Unmarshal
+func unmarshalGeneric(generic interface{}, v interface{}) error {
+	converted, err := coerce(generic, reflect.TypeOf(v))
+	if err != nil {
+		return err
+	}
+	jsonData, err := json.Marshal(converted)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(jsonData, v)
+}
+
+// decodeGeneric decodes data into maps, slices and scalars
+func decodeGeneric(data []byte, format string) (interface{}, error) {
+	var generic interface{}
+	switch format {
+	case FormatJSON, "":
+		if err := json.Unmarshal(data, &generic); err != nil {
+			return nil, err
+		}
+	case FormatYAML:
+		if err := yaml.Unmarshal(data, &generic); err != nil {
+			return nil, err
+		}
+	case FormatTOML:
+		var table map[string]interface{}
+		if err := toml.Unmarshal(data, &table); err != nil {
+			return nil, err
+		}
+		generic = table
+	default:
+		return nil, fmt.Errorf("unrecognized format: %s", format)
+	}
+	return generic, nil
+}
+
+// coerce converts decoded YAML or TOML values to what encoding/json
+// can unmarshal into t
+func coerce(value interface{}, t reflect.Type) (interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		switch v := value.(type) {
+		case bool, int, int64, uint64, float64:
+			return fmt.Sprint(v), nil
+		}
+	case reflect.Slice:
+		// TOML decodes arrays of tables as []map[string]interface{}
+		list := reflect.ValueOf(value)
+		if value == nil || list.Kind() != reflect.Slice {
+			break
+		}
+		result := make([]interface{}, list.Len())
+		for i := range result {
+			converted, err := coerce(list.Index(i).Interface(), t.Elem())
+			if err != nil {
+				return nil, err
+			}
+			result[i] = converted
+		}
+		return result, nil
+	case reflect.Struct:
+		obj, ok := toStringMap(value)
+		if !ok {
+			break
+		}
+		fields := jsonFields(t)
+		result := make(map[string]interface{}, len(obj))
+		for key, item := range obj {
+			field, ok := fields[key]
+			if !ok {
+				result[key] = item
+				continue
+			}
+			converted, err := coerce(item, field)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			result[key] = converted
+		}
+		return result, nil
+	case reflect.Map:
+		obj, ok := toStringMap(value)
+		if !ok {
+			break
+		}
+		result := make(map[string]interface{}, len(obj))
+		for key, item := range obj {
+			converted, err := coerce(item, t.Elem())
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			result[key] = converted
+		}
+		return result, nil
+	case reflect.Interface:
+		if obj, ok := toStringMap(value); ok {
+			return coerce(obj, reflect.TypeOf(map[string]interface{}{}))
+		}
+		if value != nil && reflect.TypeOf(value).Kind() == reflect.Slice {
+			return coerce(value, reflect.TypeOf([]interface{}{}))
+		}
+	}
+	return value, nil
+}
+
+// toStringMap accepts both map[string]interface{} and the
+// map[interface{}]interface{} some YAML documents decode to
+func toStringMap(value interface{}) (map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v, true
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[fmt.Sprint(key)] = item
+		}
+		return result, true
+	}
+	return nil, false
+}
+
+// jsonFields maps json field names of struct t, including
+// those of embedded structs, to their types
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for k, v := range jsonFields(embedded) {
+					fields[k] = v
+				}
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
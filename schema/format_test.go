@@ -0,0 +1,86 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/xhd2015/cli2web/config"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		file     string
+		data     string
+		expected string
+	}{
+		{"schema.json", "", FormatJSON},
+		{"schema.YML", "", FormatYAML},
+		{"schema.toml", "", FormatTOML},
+		{"", `{"name": "kool"}`, FormatJSON},
+		{"", "\n  [\n]", FormatJSON},
+		{"", "# kool\nname: kool\n", FormatYAML},
+		{"", "- a\n", FormatYAML},
+		{"", "# kool\nname = \"kool\"\n", FormatTOML},
+		{"", "[[options]]\nflags = \"-v\"\n", FormatTOML},
+		{"schema", "name: kool", FormatYAML},
+	}
+	for _, tt := range tests {
+		if actual := DetectFormat(tt.file, []byte(tt.data)); actual != tt.expected {
+			t.Errorf("DetectFormat(%q, %q) = %s, expected %s", tt.file, tt.data, actual, tt.expected)
+		}
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	sources := map[string]string{
+		FormatYAML: `
+name: kool
+persistentPlacement: before
+commands:
+  - name: git
+    deprecated: use kool vcs
+    options:
+      - flags: --retries <n>
+        type: number
+        default: 3
+      - flags: --push
+        type: boolean
+        default: true
+`,
+		FormatTOML: `
+name = "kool"
+persistentPlacement = "before"
+
+[[commands]]
+name = "git"
+deprecated = "use kool vcs"
+
+[[commands.options]]
+flags = "--retries <n>"
+type = "number"
+default = 3
+
+[[commands.options]]
+flags = "--push"
+type = "boolean"
+default = true
+`,
+	}
+	for format, source := range sources {
+		t.Run(format, func(t *testing.T) {
+			var s *config.Schema
+			if err := Unmarshal([]byte(source), format, &s); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if s.Name != "kool" || s.PersistentPlacement != config.PlacementBefore {
+				t.Errorf("Unexpected root %+v", s)
+			}
+			if len(s.Commands) != 1 || s.Commands[0].Deprecated != "use kool vcs" {
+				t.Fatalf("Unexpected commands %+v", s.Commands)
+			}
+			options := s.Commands[0].Options
+			if len(options) != 2 || options[0].Default != "3" || options[1].Default != "true" {
+				t.Errorf("Unexpected options %+v", options)
+			}
+		})
+	}
+}
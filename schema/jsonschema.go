@@ -0,0 +1,167 @@
+package schema
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/xhd2015/cli2web/config"
+)
+
+// JSONSchemaDraft is the dialect of the JSON Schema returned by GenerateJSONSchema
+const JSONSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// GenerateJSONSchema returns a JSON Schema describing the schema format,
+// generated from the config structs. Descriptions and enums come from
+// their desc and enum tags. The root accepts a "$schema" key so that
+// editors can pick up the JSON Schema, and "definitions" for $ref.
+func GenerateJSONSchema() []byte {
+	g := &jsonSchemaGenerator{
+		defs: make(map[string]*orderedObject),
+	}
+	properties := newOrderedObject(
+		"$schema", newOrderedObject(
+			"type", "string",
+			"description", "URL or path of the JSON Schema, for editors",
+		),
+		definitionsKey, newOrderedObject(
+			"type", "object",
+			"description", "values that \"#/definitions/<name>\" refers to",
+		),
+	)
+	commandProperties := g.properties(reflect.TypeOf(config.Schema{}))
+	properties.keys = append(properties.keys, commandProperties.keys...)
+	properties.values = append(properties.values, commandProperties.values...)
+
+	names := make([]string, 0, len(g.defs))
+	for name := range g.defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	defs := newOrderedObject()
+	for _, name := range names {
+		defs.keys = append(defs.keys, name)
+		defs.values = append(defs.values, g.defs[name])
+	}
+
+	root := newOrderedObject(
+		"$schema", JSONSchemaDraft,
+		"title", "cli2web schema",
+		"type", "object",
+		"properties", properties,
+		"additionalProperties", false,
+		"$defs", defs,
+	)
+	var buf strings.Builder
+	writeOrdered(&buf, root, "")
+	buf.WriteString("\n")
+	return []byte(buf.String())
+}
+
+type jsonSchemaGenerator struct {
+	defs map[string]*orderedObject
+}
+
+// typeSchema returns the schema of t, structs are
+// referenced from $defs
+func (g *jsonSchemaGenerator) typeSchema(t reflect.Type) *orderedObject {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if _, ok := g.defs[t.Name()]; !ok {
+			// registered before the properties for recursive types
+			def := newOrderedObject()
+			g.defs[t.Name()] = def
+			*def = *newOrderedObject(
+				"type", "object",
+				"properties", g.refProperties(t),
+				"additionalProperties", false,
+			)
+		}
+		return newOrderedObject("$ref", "#/$defs/"+t.Name())
+	case reflect.Slice:
+		return newOrderedObject("type", "array", "items", g.typeSchema(t.Elem()))
+	case reflect.Bool:
+		return newOrderedObject("type", "boolean")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return newOrderedObject("type", "integer")
+	case reflect.Float32, reflect.Float64:
+		return newOrderedObject("type", "number")
+	}
+	return newOrderedObject("type", "string")
+}
+
+// refProperties are the properties of t with the keys that are
+// expanded before decoding
+func (g *jsonSchemaGenerator) refProperties(t reflect.Type) *orderedObject {
+	properties := newOrderedObject(
+		refKey, newOrderedObject(
+			"type", "string",
+			"description", "file and/or JSON pointer of a value to use, other fields override it",
+		),
+	)
+	if t == commandType {
+		properties.keys = append(properties.keys, includeKey)
+		properties.values = append(properties.values, newOrderedObject(
+			"type", "string",
+			"description", "schema file or directory to read the command from, other fields override it",
+		))
+	}
+	own := g.properties(t)
+	properties.keys = append(properties.keys, own.keys...)
+	properties.values = append(properties.values, own.values...)
+	return properties
+}
+
+// properties lists the json fields of struct t in declaration
+// order, including those of embedded structs
+func (g *jsonSchemaGenerator) properties(t reflect.Type) *orderedObject {
+	properties := newOrderedObject()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			embedded := g.properties(field.Type)
+			properties.keys = append(properties.keys, embedded.keys...)
+			properties.values = append(properties.values, embedded.values...)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		prop := g.typeSchema(field.Type)
+		if desc := field.Tag.Get("desc"); desc != "" {
+			prop.keys = append(prop.keys, "description")
+			prop.values = append(prop.values, desc)
+		}
+		if enum := field.Tag.Get("enum"); enum != "" {
+			var values []interface{}
+			for _, value := range strings.Split(enum, ",") {
+				values = append(values, value)
+			}
+			prop.keys = append(prop.keys, "enum")
+			prop.values = append(prop.values, values)
+		}
+		properties.keys = append(properties.keys, name)
+		properties.values = append(properties.values, prop)
+	}
+	return properties
+}
+
+func newOrderedObject(keyValues ...interface{}) *orderedObject {
+	obj := &orderedObject{}
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		obj.keys = append(obj.keys, keyValues[i].(string))
+		obj.values = append(obj.values, keyValues[i+1])
+	}
+	return obj
+}
@@ -0,0 +1,33 @@
+package schema
+
+import (
+	"os"
+	"testing"
+)
+
+// the published JSON Schema is referenced by "$schema" in schema files
+const publishedJSONSchema = "../cli2web.schema.json"
+
+func TestGenerateJSONSchema(t *testing.T) {
+	generated := GenerateJSONSchema()
+	published, err := os.ReadFile(publishedJSONSchema)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", publishedJSONSchema, err)
+	}
+	if string(published) != string(generated) {
+		t.Errorf("%s is out of date, run `go run ./ jsonschema > cli2web.schema.json`", publishedJSONSchema)
+	}
+
+	// the example references it by "$schema"
+	data, err := os.ReadFile("../schema-example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, diagnostics, err := ValidateData(data, FormatJSON, "schema-example.json")
+	if err != nil {
+		t.Fatalf("ValidateData() error = %v", err)
+	}
+	if len(diagnostics) > 0 {
+		t.Errorf("Unexpected diagnostics %v", diagnostics)
+	}
+}
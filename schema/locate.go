@@ -0,0 +1,420 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/xhd2015/cli2web/config"
+	"github.com/xhd2015/cli2web/markjson"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	schemaType    = reflect.TypeOf(config.Schema{})
+	commandType   = reflect.TypeOf(config.Command{})
+	optionsType   = reflect.TypeOf([]*config.Option{})
+	argumentsType = reflect.TypeOf([]*config.Argument{})
+	settingsType  = reflect.TypeOf(commandSettings{})
+)
+
+// ValidateData parses a json, yaml or toml schema and validates it,
+// in addition to Validate it reports unknown fields. Diagnostics
+// are located by file and line, toml only has JSON pointers.
+// $ref and include are read relative to file.
+func ValidateData(data []byte, format string, file string) (*config.Schema, []*Diagnostic, error) {
+	s, err := UnmarshalSchema(data, format, NewFSSchemaDir(filepath.Dir(file)), filepath.Base(file))
+	if err != nil {
+		return nil, nil, err
+	}
+	if s == nil {
+		s = &config.Schema{}
+	}
+	generic, err := decodeGeneric(data, format)
+	if err != nil {
+		return nil, nil, err
+	}
+	if obj, ok := toStringMap(generic); ok {
+		// editors read the JSON Schema from "$schema"
+		delete(obj, "$schema")
+		delete(obj, definitionsKey)
+		generic = obj
+	}
+	var diagnostics []*Diagnostic
+	checkFields(generic, schemaType, "", &diagnostics)
+	diagnostics = append(diagnostics, Validate(s)...)
+
+	lines := dataLines(data, format, "", 0)
+	for _, d := range diagnostics {
+		d.File = file
+		d.Line = lookupLine(lines, d.Pointer)
+	}
+	sortDiagnostics(diagnostics)
+	return s, diagnostics, nil
+}
+
+// ValidateDir parses a markdown directory schema and validates it,
+// in addition to Validate it reports unknown fields in the options,
+// arguments and settings sections. Diagnostics are located by the
+// markdown file, relative to rootDir, and line.
+func ValidateDir(rootDir SchemaDir) (*config.Schema, []*Diagnostic, error) {
+	sources := make(sourceMap)
+	s, err := parseSchema(rootDir, sources)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var diagnostics []*Diagnostic
+	var locations []*commandLocation
+	var walk func(cmd *config.Command, pointer string) error
+	walk = func(cmd *config.Command, pointer string) error {
+		if source := sources[cmd]; source != nil {
+			loc, err := locateCommand(source, pointer, &diagnostics)
+			if err != nil {
+				return err
+			}
+			locations = append(locations, loc)
+		}
+		for i, sub := range cmd.Commands {
+			if err := walk(sub, fmt.Sprintf("%s/commands/%d", pointer, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(s, ""); err != nil {
+		return nil, nil, err
+	}
+
+	diagnostics = append(diagnostics, Validate(s)...)
+	for _, d := range diagnostics {
+		if loc := findLocation(locations, d.Pointer); loc != nil {
+			d.File = loc.source.file
+			d.Line = lookupLine(loc.lines, d.Pointer)
+			if d.Line == 0 && loc.source.content != "" {
+				d.Line = 1
+			}
+		}
+	}
+	sortDiagnostics(diagnostics)
+	return s, diagnostics, nil
+}
+
+// commandLocation maps the JSON pointers of a command parsed
+// from markdown to lines of its file
+type commandLocation struct {
+	pointer string
+	source  *commandSource
+	lines   map[string]int
+}
+
+// locateCommand maps the data sections of a markdown command file,
+// unknown fields found in them are added to diagnostics
+func locateCommand(source *commandSource, pointer string, diagnostics *[]*Diagnostic) (*commandLocation, error) {
+	loc := &commandLocation{
+		pointer: pointer,
+		source:  source,
+		lines:   make(map[string]int),
+	}
+	if source.content == "" {
+		return loc, nil
+	}
+	sections, err := markjson.ParseTree(source.content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", source.file, err)
+	}
+	sections, _ = commandSections(sections)
+	dataSections := []struct {
+		title   string
+		pointer string
+		typ     reflect.Type
+	}{
+		{"options", pointer + "/options", optionsType},
+		{"arguments", pointer + "/arguments", argumentsType},
+		{"settings", pointer, settingsType},
+	}
+	for _, sec := range dataSections {
+		section := sections.Find(sec.title)
+		if section == nil {
+			continue
+		}
+		snippet := findDataSnippet(section.Snippets)
+		if snippet == nil {
+			if table := section.Snippets.FindType(markjson.Table); table != nil && sec.typ.Kind() == reflect.Slice {
+				if err := locateTable(table, sec.typ, sec.pointer, loc, diagnostics); err != nil {
+					return nil, fmt.Errorf("failed to parse %s in %s: %w", sec.title, source.file, err)
+				}
+			}
+			continue
+		}
+		format := FormatOfLanguage(snippet.Language)
+		generic, err := decodeGeneric([]byte(snippet.Content), format)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s in %s: %w", sec.title, source.file, err)
+		}
+		checkFields(generic, sec.typ, sec.pointer, diagnostics)
+
+		// lines of the content are numbered after the ones before it
+		for p, line := range dataLines([]byte(snippet.Content), format, sec.pointer, snippet.Start.Line-1) {
+			loc.lines[p] = line
+		}
+	}
+	return loc, nil
+}
+
+// locateTable maps the rows of a table written for the
+// slice type t, each row and its cells map to the row's line
+func locateTable(table *markjson.Snippet, t reflect.Type, pointer string, loc *commandLocation, diagnostics *[]*Diagnostic) error {
+	rows, err := tableData(table, t.Elem())
+	if err != nil {
+		return err
+	}
+	checkFields(rows, t, pointer, diagnostics)
+	for i, row := range rows {
+		rowPointer := fmt.Sprintf("%s/%d", pointer, i)
+		line := tableRowLine(table, i)
+		loc.lines[rowPointer] = line
+		obj, _ := toStringMap(row)
+		for key := range obj {
+			loc.lines[rowPointer+"/"+escapePointer(key)] = line
+		}
+	}
+	return nil
+}
+
+// findLocation finds the innermost command containing pointer
+func findLocation(locations []*commandLocation, pointer string) *commandLocation {
+	var found *commandLocation
+	for _, loc := range locations {
+		if pointer != loc.pointer && !strings.HasPrefix(pointer, loc.pointer+"/") {
+			continue
+		}
+		if found == nil || len(loc.pointer) > len(found.pointer) {
+			found = loc
+		}
+	}
+	return found
+}
+
+// checkFields reports object keys of value that do not
+// match a json field of the type t expects
+func checkFields(value interface{}, t reflect.Type, pointer string, diagnostics *[]*Diagnostic) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := toStringMap(value)
+		if !ok {
+			return
+		}
+		fields := jsonFields(t)
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fieldPointer := pointer + "/" + escapePointer(key)
+			if key == refKey || (key == includeKey && t == commandType) {
+				// expanded before decoding
+				continue
+			}
+			field, ok := fields[key]
+			if !ok {
+				msg := fmt.Sprintf("unknown field %q", key)
+				if suggestion := suggestField(key, fields); suggestion != "" {
+					msg += fmt.Sprintf(", did you mean %q?", suggestion)
+				}
+				*diagnostics = append(*diagnostics, &Diagnostic{Pointer: fieldPointer, Message: msg})
+				continue
+			}
+			checkFields(obj[key], field, fieldPointer, diagnostics)
+		}
+	case reflect.Slice:
+		list := reflect.ValueOf(value)
+		if value == nil || list.Kind() != reflect.Slice {
+			return
+		}
+		for i := 0; i < list.Len(); i++ {
+			checkFields(list.Index(i).Interface(), t.Elem(), fmt.Sprintf("%s/%d", pointer, i), diagnostics)
+		}
+	}
+}
+
+// suggestField finds a known field close to an unknown key,
+// e.g. "flags" for "flag"
+func suggestField(key string, fields map[string]reflect.Type) string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var best string
+	bestDistance := 3
+	for _, name := range names {
+		if d := editDistance(strings.ToLower(key), strings.ToLower(name)); d < bestDistance {
+			best, bestDistance = name, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, minInt(cur[j-1]+1, prev[j-1]+cost))
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// dataLines maps JSON pointers, prefixed by prefix, to the lines
+// where they appear in data, shifted by offset. toml is not mapped.
+func dataLines(data []byte, format string, prefix string, offset int) map[string]int {
+	switch format {
+	case FormatJSON, "":
+		return jsonLines(data, prefix, offset)
+	case FormatYAML:
+		return yamlLines(data, prefix, offset)
+	}
+	return map[string]int{}
+}
+
+func jsonLines(data []byte, prefix string, offset int) map[string]int {
+	lines := make(map[string]int)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	lineAt := func(pos int64) int {
+		// the offset before a token may still be at the
+		// preceding separator
+		for int(pos) < len(data) && strings.IndexByte(" \t\r\n,:", data[pos]) >= 0 {
+			pos++
+		}
+		return bytes.Count(data[:pos], []byte("\n")) + 1 + offset
+	}
+	var walk func(pointer string) error
+	walk = func(pointer string) error {
+		start := dec.InputOffset()
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if _, ok := lines[pointer]; !ok {
+			lines[pointer] = lineAt(start)
+		}
+		switch tok {
+		case json.Delim('{'):
+			for dec.More() {
+				keyStart := dec.InputOffset()
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				keyPointer := pointer + "/" + escapePointer(key.(string))
+				lines[keyPointer] = lineAt(keyStart)
+				if err := walk(keyPointer); err != nil {
+					return err
+				}
+			}
+			_, err := dec.Token()
+			return err
+		case json.Delim('['):
+			for i := 0; dec.More(); i++ {
+				if err := walk(fmt.Sprintf("%s/%d", pointer, i)); err != nil {
+					return err
+				}
+			}
+			_, err := dec.Token()
+			return err
+		}
+		return nil
+	}
+	// invalid data is reported when unmarshaling,
+	// the lines mapped so far are still useful
+	walk(prefix)
+	return lines
+}
+
+func yamlLines(data []byte, prefix string, offset int) map[string]int {
+	lines := make(map[string]int)
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return lines
+	}
+	var walk func(node *yaml.Node, pointer string)
+	walk = func(node *yaml.Node, pointer string) {
+		if _, ok := lines[pointer]; !ok {
+			lines[pointer] = node.Line + offset
+		}
+		switch node.Kind {
+		case yaml.DocumentNode:
+			for _, child := range node.Content {
+				walk(child, pointer)
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key := node.Content[i]
+				keyPointer := pointer + "/" + escapePointer(key.Value)
+				lines[keyPointer] = key.Line + offset
+				walk(node.Content[i+1], keyPointer)
+			}
+		case yaml.SequenceNode:
+			for i, child := range node.Content {
+				walk(child, fmt.Sprintf("%s/%d", pointer, i))
+			}
+		}
+	}
+	walk(&doc, prefix)
+	return lines
+}
+
+// lookupLine finds the line of pointer, or of its nearest
+// mapped ancestor, 0 if none is mapped
+func lookupLine(lines map[string]int, pointer string) int {
+	for {
+		if line, ok := lines[pointer]; ok {
+			return line
+		}
+		idx := strings.LastIndex(pointer, "/")
+		if idx < 0 {
+			return 0
+		}
+		pointer = pointer[:idx]
+	}
+}
+
+func escapePointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
+
+func sortDiagnostics(diagnostics []*Diagnostic) {
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		if diagnostics[i].File != diagnostics[j].File {
+			return diagnostics[i].File < diagnostics[j].File
+		}
+		return diagnostics[i].Line < diagnostics[j].Line
+	})
+}
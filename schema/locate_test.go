@@ -0,0 +1,101 @@
+package schema
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestValidateData(t *testing.T) {
+	sources := map[string]string{
+		"schema.json": `{
+    "name": "kool",
+    "commands": [
+        {
+            "name": "git",
+            "options": [
+                {"flag": "--push", "type": "boolean"},
+                {"flags": "--retries <n>", "type": "number", "default": "x"}
+            ]
+        }
+    ]
+}`,
+		"schema.yaml": `name: kool
+commands:
+  - name: git
+    options:
+      - flag: --push
+        type: boolean
+      - flags: --retries <n>
+        type: number
+        default: x
+`,
+	}
+	expected := map[string][]string{
+		"schema.json": {
+			`schema.json:7: unknown field "flag", did you mean "flags"?`,
+			`schema.json:7: kool git: option flags are empty`,
+			`schema.json:8: kool git --retries <n>: default "x" is not a number`,
+		},
+		"schema.yaml": {
+			`schema.yaml:5: unknown field "flag", did you mean "flags"?`,
+			`schema.yaml:5: kool git: option flags are empty`,
+			`schema.yaml:9: kool git --retries <n>: default "x" is not a number`,
+		},
+	}
+	for file, source := range sources {
+		t.Run(file, func(t *testing.T) {
+			_, diagnostics, err := ValidateData([]byte(source), DetectFormat(file, nil), file)
+			if err != nil {
+				t.Fatalf("ValidateData() error = %v", err)
+			}
+			compareDiagnostics(t, diagnostics, expected[file])
+		})
+	}
+}
+
+func TestValidateData_Refs(t *testing.T) {
+	source := `{
+    "name": "kool",
+    "definitions": {"push": {"flags": "--push", "type": "boolean"}},
+    "options": [
+        {"$ref": "#/definitions/push", "descriptin": "Push"}
+    ]
+}`
+	_, diagnostics, err := ValidateData([]byte(source), FormatJSON, "schema.json")
+	if err != nil {
+		t.Fatalf("ValidateData() error = %v", err)
+	}
+	compareDiagnostics(t, diagnostics, []string{
+		`schema.json:5: unknown field "descriptin", did you mean "description"?`,
+	})
+}
+
+func TestValidateDir(t *testing.T) {
+	fsys := fstest.MapFS{
+		"kool/kool.md":        {Data: []byte("# Description\nKool\n")},
+		"kool/git/git.md":     {Data: []byte("# Description\nGit\n\n# Options\n```json\n[\n    {\n        \"flags\": \"--push\",\n        \"typ\": \"boolean\"\n    }\n]\n```\n")},
+		"kool/git/tag/tag.md": {Data: []byte("# Arguments\n```yaml\n- name: dir\n- name: dir\n```\n")},
+		"kool/version.md":     {Data: []byte("# Options\n| flags | typ |\n|---|---|\n| `--short` | boolean |\n")},
+	}
+	_, diagnostics, err := ValidateDir(NewGenericFSSchemaDir(fsys, "kool"))
+	if err != nil {
+		t.Fatalf("ValidateDir() error = %v", err)
+	}
+	compareDiagnostics(t, diagnostics, []string{
+		`git/git.md:9: unknown field "typ", did you mean "type"?`,
+		`git/tag/tag.md:4: kool git tag dir: duplicate argument "dir"`,
+		`version.md:4: unknown field "typ", did you mean "type"?`,
+	})
+}
+
+func compareDiagnostics(t *testing.T, diagnostics []*Diagnostic, expected []string) {
+	t.Helper()
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("Expected %d diagnostics, got %d: %v", len(expected), len(diagnostics), diagnostics)
+	}
+	for i, d := range diagnostics {
+		if d.String() != expected[i] {
+			t.Errorf("Diagnostic %d = %q, expected %q", i, d.String(), expected[i])
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package schema
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// compactJSON marshals v with indentation, leaving out zero-valued
+// fields to keep hand-edited files short. Field order is preserved.
+func compactJSON(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.UseNumber()
+	value, err := decodeOrdered(dec, true)
+	if err != nil {
+		return nil, err
+	}
+	var buf strings.Builder
+	writeOrdered(&buf, value, "")
+	return []byte(buf.String()), nil
+}
+
+// orderedObject is a JSON object that keeps its key order
+type orderedObject struct {
+	keys   []string
+	values []interface{}
+}
+
+// decodeOrdered decodes the next JSON value keeping the key order
+// of objects, with dropZero, zero-valued fields are left out
+func decodeOrdered(dec *json.Decoder, dropZero bool) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		obj := &orderedObject{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrdered(dec, dropZero)
+			if err != nil {
+				return nil, err
+			}
+			if dropZero && isZero(value) {
+				continue
+			}
+			obj.keys = append(obj.keys, key.(string))
+			obj.values = append(obj.values, value)
+		}
+		_, err := dec.Token()
+		return obj, err
+	case json.Delim('['):
+		list := []interface{}{}
+		for dec.More() {
+			value, err := decodeOrdered(dec, dropZero)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err := dec.Token()
+		return list, err
+	}
+	return tok, nil
+}
+
+func isZero(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	case string:
+		return v == ""
+	case json.Number:
+		return v == "0"
+	case []interface{}:
+		return len(v) == 0
+	case *orderedObject:
+		return len(v.keys) == 0
+	}
+	return false
+}
+
+func writeOrdered(buf *strings.Builder, v interface{}, indent string) {
+	const step = "    "
+	switch v := v.(type) {
+	case *orderedObject:
+		buf.WriteString("{")
+		for i, key := range v.keys {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			buf.WriteString("\n" + indent + step)
+			writeScalar(buf, key)
+			buf.WriteString(": ")
+			writeOrdered(buf, v.values[i], indent+step)
+		}
+		if len(v.keys) > 0 {
+			buf.WriteString("\n" + indent)
+		}
+		buf.WriteString("}")
+	case []interface{}:
+		buf.WriteString("[")
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			buf.WriteString("\n" + indent + step)
+			writeOrdered(buf, item, indent+step)
+		}
+		if len(v) > 0 {
+			buf.WriteString("\n" + indent)
+		}
+		buf.WriteString("]")
+	default:
+		writeScalar(buf, v)
+	}
+}
+
+func writeScalar(buf *strings.Builder, v interface{}) {
+	var sb strings.Builder
+	enc := json.NewEncoder(&sb)
+	// keep flags like "--format <fmt>" readable
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+	buf.WriteString(strings.TrimSuffix(sb.String(), "\n"))
+}
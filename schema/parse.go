@@ -2,28 +2,112 @@ package schema
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/xhd2015/cli2web/config"
 	"github.com/xhd2015/cli2web/markjson"
+	"gopkg.in/yaml.v3"
 )
 
 // parseCommandFromMarkdown parses a markdown file to extract command definition
 func parseCommandFromMarkdown(file SchemaFile, defaultName string) (*config.Command, error) {
+	return parseCommandMarkdown(file, &commandFile{rel: file.Name(), refs: newResolver(nil)}, defaultName)
+}
+
+// commandFile is a markdown command file being parsed
+type commandFile struct {
+	// path names the file in errors
+	path string
+	// rel is the path of the file relative to the root of refs
+	rel  string
+	refs *resolver
+	// definitions are those of the file and of the directories
+	// above it, resolved
+	definitions map[string]interface{}
+}
+
+// parseCommandMarkdown parses the command of file, the definitions
+// section of the file is added to f.definitions
+func parseCommandMarkdown(file SchemaFile, f *commandFile, defaultName string) (*config.Command, error) {
+	if f.path == "" {
+		f.path = filePath(file)
+	}
 	content, err := file.Read()
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file %s: %w", file.Name(), err)
 	}
 
+	// Parse the markdown content
+	doc, err := markjson.ParseDocument(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse markdown content: %w", err)
+	}
+	var settings *commandSettings
+	if doc.FrontMatter != nil {
+		// front matter holds the same fields as the settings section,
+		// keys of docs sites like title or layout are ignored
+		settings = &commandSettings{}
+		if err := unmarshalGeneric(doc.FrontMatter, settings); err != nil {
+			return nil, fmt.Errorf("failed to parse front matter: %w", err)
+		}
+	}
+	sections, nested := commandSections(doc.Tree())
+	return parseCommandSections(f, sections, defaultName, nested, settings)
+}
+
+// commandFieldSections are the sections holding fields of a command,
+// in the order they are written
+var commandFieldSections = []string{"description", "options", "arguments", "examples", "settings", "definitions"}
+
+func isCommandFieldSection(title string) bool {
+	return containsString(commandFieldSections, strings.ToLower(title))
+}
+
+// commandSections returns the sections describing the command of a file.
+// A file may wrap the command in a single header, e.g. "# go" followed
+// by "## Options". Then other headers under it are nested subcommands.
+func commandSections(sections markjson.Sections) (markjson.Sections, bool) {
+	if len(sections) == 1 && !isCommandFieldSection(sections[0].Title) {
+		return sections[0].Children, true
+	}
+	return sections, false
+}
+
+// isSubcommandSection reports whether a section that is not a command
+// field describes a subcommand: it has field sections or subcommands
+// under it, or is a bare header like "## version". Other sections,
+// e.g. "## Notes" with text, are prose and ignored.
+func isSubcommandSection(section *markjson.Section) bool {
+	if len(section.Snippets) == 0 && len(section.Children) == 0 {
+		return true
+	}
+	for _, child := range section.Children {
+		if isCommandFieldSection(child.Title) || isSubcommandSection(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCommandSections parses a command from its sections, with nested
+// set, the sections that are not command fields are subcommands named
+// by their title. The settings section overrides fields of settings.
+func parseCommandSections(f *commandFile, sections markjson.Sections, defaultName string, nested bool, settings *commandSettings) (*config.Command, error) {
 	cmd := &config.Command{
 		Name: defaultName,
 	}
 
-	// Parse the markdown content
-	sections, err := markjson.Parse(string(content))
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse markdown content: %w", err)
+	// definitions come first, the other sections refer to them
+	if section := sections.Find("definitions"); section != nil {
+		if snippet := findDataSnippet(section.Snippets); snippet != nil {
+			if err := f.define(snippet); err != nil {
+				return nil, fmt.Errorf("failed to parse definitions %s: %w", strings.ToUpper(snippet.Language), err)
+			}
+		}
 	}
 
 	// Parse description from dedicated section first
@@ -37,9 +121,15 @@ func parseCommandFromMarkdown(file SchemaFile, defaultName string) (*config.Comm
 	// Parse options
 	if section := sections.Find("options"); section != nil {
 		var options []*config.Option
-		if jsonSnippet := section.Snippets.FindJson(); jsonSnippet != nil {
-			if err := json.Unmarshal([]byte(jsonSnippet.Content), &options); err != nil {
-				return nil, fmt.Errorf("failed to parse options JSON: %w", err)
+		if snippet := findDataSnippet(section.Snippets); snippet != nil {
+			if err := f.unmarshal(snippet, &options); err != nil {
+				return nil, fmt.Errorf("failed to parse options %s: %w", strings.ToUpper(snippet.Language), err)
+			}
+			cmd.Options = options
+		} else if table := section.Snippets.FindType(markjson.Table); table != nil {
+			// written as a markdown table for readers
+			if err := unmarshalTable(f.path, table, &options); err != nil {
+				return nil, fmt.Errorf("failed to parse options table: %w", err)
 			}
 			cmd.Options = options
 		}
@@ -48,65 +138,301 @@ func parseCommandFromMarkdown(file SchemaFile, defaultName string) (*config.Comm
 	// Parse arguments
 	if section := sections.Find("arguments"); section != nil {
 		var arguments []*config.Argument
-		if jsonSnippet := section.Snippets.FindJson(); jsonSnippet != nil {
-			if err := json.Unmarshal([]byte(jsonSnippet.Content), &arguments); err != nil {
-				return nil, fmt.Errorf("failed to parse arguments JSON: %w", err)
+		if snippet := findDataSnippet(section.Snippets); snippet != nil {
+			if err := f.unmarshal(snippet, &arguments); err != nil {
+				return nil, fmt.Errorf("failed to parse arguments %s: %w", strings.ToUpper(snippet.Language), err)
+			}
+			cmd.Arguments = arguments
+		} else if table := section.Snippets.FindType(markjson.Table); table != nil {
+			// written as a markdown table for readers
+			if err := unmarshalTable(f.path, table, &arguments); err != nil {
+				return nil, fmt.Errorf("failed to parse arguments table: %w", err)
 			}
 			cmd.Arguments = arguments
 		}
 	}
 
 	if section := sections.Find("examples"); section != nil {
-		var examples []*config.Example
-
-		n := len(section.Snippets)
-		var descriptions []string
-		for i := 0; i < n; i++ {
-			snippet := section.Snippets[i]
-			if snippet.Type != markjson.Code {
-				descriptions = append(descriptions, snippet.Content)
-				continue
-			}
-			examples = append(examples, &config.Example{
-				Usage:       snippet.Content,
-				Description: strings.Join(descriptions, "\n"),
-			})
-			descriptions = nil
-		}
-		if len(descriptions) > 0 {
-			examples = append(examples, &config.Example{
-				Usage:       "",
-				Description: strings.Join(descriptions, "\n"),
-			})
-		}
-		cmd.Examples = examples
+		cmd.Examples = parseExamples(section.Snippets)
 	}
 
 	// Parse settings
 	if section := sections.Find("settings"); section != nil {
-		var settings map[string]interface{}
-		if jsonSnippet := section.Snippets.FindJson(); jsonSnippet != nil {
-			if err := json.Unmarshal([]byte(jsonSnippet.Content), &settings); err != nil {
-				return nil, fmt.Errorf("failed to parse settings JSON: %w", err)
+		if snippet := findDataSnippet(section.Snippets); snippet != nil {
+			if err := f.unmarshal(snippet, &settings); err != nil {
+				return nil, fmt.Errorf("failed to parse settings %s: %w", strings.ToUpper(snippet.Language), err)
 			}
 		}
-
-		// Override command name if specified in settings
-		if name, ok := settings["name"].(string); ok && name != "" {
-			cmd.Name = name
+	}
+	if settings != nil {
+		settings.apply(cmd)
+		if settings.Include != "" {
+			included, err := f.include(settings.Include, cmd)
+			if err != nil {
+				return nil, err
+			}
+			cmd = included
 		}
+	}
 
-		// Set description if specified in settings (only if not already set from description section)
-		if cmd.Description == "" {
-			if desc, ok := settings["description"].(string); ok {
-				cmd.Description = desc
+	if nested {
+		for _, section := range sections {
+			if isCommandFieldSection(section.Title) || !isSubcommandSection(section) {
+				continue
+			}
+			// definitions of a nested command stay in it
+			subFile := *f
+			sub, err := parseCommandSections(&subFile, section.Children, section.Title, true, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse command %s: %w", section.Title, err)
 			}
+			cmd.Commands = append(cmd.Commands, sub)
 		}
 	}
 
 	return cmd, nil
 }
 
+// parseExamples pairs each code block with the text before it,
+// text after the last block is an example without usage
+func parseExamples(snippets markjson.Snippets) []*config.Example {
+	var examples []*config.Example
+	var descriptions []string
+	for _, snippet := range snippets {
+		if snippet.Type != markjson.Code {
+			descriptions = append(descriptions, snippet.Content)
+			continue
+		}
+		examples = append(examples, &config.Example{
+			Usage:       snippet.Content,
+			Description: strings.Join(descriptions, "\n"),
+		})
+		descriptions = nil
+	}
+	if len(descriptions) > 0 {
+		examples = append(examples, &config.Example{
+			Usage:       "",
+			Description: strings.Join(descriptions, "\n"),
+		})
+	}
+	return examples
+}
+
+// commandSettings are the command fields set by the # Settings
+// section, those without a section of their own
+type commandSettings struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Aliases     []string       `json:"aliases"`
+	Output      *config.Output `json:"output"`
+	// Examples that a # Examples section can't hold as written
+	Examples            []*config.Example     `json:"examples"`
+	Groups              []*config.OptionGroup `json:"groups"`
+	PersistentPlacement string                `json:"persistentPlacement"`
+	Order               int                   `json:"order"`
+	// Weight is Order by the name docs sites use in front matter
+	Weight int `json:"weight"`
+	// Include is a schema file or directory the command is read
+	// from, the fields set by the markdown file override it
+	Include string `json:"include"`
+
+	config.Lifecycle
+}
+
+func (s *commandSettings) apply(cmd *config.Command) {
+	// Override command name if specified in settings
+	if s.Name != "" {
+		cmd.Name = s.Name
+	}
+	// Set description if specified in settings (only if not already set from description section)
+	if cmd.Description == "" {
+		cmd.Description = s.Description
+	}
+	if len(cmd.Examples) == 0 {
+		cmd.Examples = s.Examples
+	}
+	cmd.Aliases = s.Aliases
+	cmd.Output = s.Output
+	cmd.Groups = s.Groups
+	cmd.PersistentPlacement = s.PersistentPlacement
+	cmd.Order = s.Order
+	if cmd.Order == 0 {
+		cmd.Order = s.Weight
+	}
+	cmd.Lifecycle = s.Lifecycle
+}
+
+// define resolves the definitions of snippet and adds them
+// to those visible in the file
+func (f *commandFile) define(snippet *markjson.Snippet) error {
+	var own map[string]interface{}
+	if err := unmarshalSnippet(f.path, snippet, &own); err != nil {
+		return err
+	}
+	scope := make(map[string]interface{}, len(f.definitions)+len(own))
+	for name, value := range f.definitions {
+		scope[name] = value
+	}
+	for name, value := range own {
+		scope[name] = value
+	}
+	doc := &refDoc{file: f.rel, root: map[string]interface{}{definitionsKey: scope}}
+	for name, value := range own {
+		resolved, err := f.refs.resolve(value, doc)
+		if err != nil {
+			return f.errorAt(snippet, err)
+		}
+		scope[name] = resolved
+	}
+	f.definitions = scope
+	return nil
+}
+
+// unmarshal decodes a data snippet into v, expanding $ref
+func (f *commandFile) unmarshal(snippet *markjson.Snippet, v interface{}) error {
+	var generic interface{}
+	if err := unmarshalSnippet(f.path, snippet, &generic); err != nil {
+		return err
+	}
+	if !hasRefs(generic, false) {
+		// decoded again to locate type errors
+		return unmarshalSnippet(f.path, snippet, v)
+	}
+	doc := &refDoc{file: f.rel, root: map[string]interface{}{definitionsKey: f.definitions}}
+	resolved, err := f.refs.resolve(generic, doc)
+	if err != nil {
+		return f.errorAt(snippet, err)
+	}
+	return unmarshalGeneric(resolved, v)
+}
+
+// include returns the command included by the file, the fields
+// set by cmd override those of the included command
+func (f *commandFile) include(file string, cmd *config.Command) (*config.Command, error) {
+	doc := &refDoc{file: f.rel, includes: true}
+	base, err := f.refs.include(file, doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.path, err)
+	}
+	merged, ok := toStringMap(base)
+	if !ok {
+		return nil, fmt.Errorf("%s: %s %q is not a command", f.path, includeKey, file)
+	}
+	data, err := compactJSON(cmd)
+	if err != nil {
+		return nil, err
+	}
+	var overlay map[string]interface{}
+	if err := json.Unmarshal(data, &overlay); err != nil {
+		return nil, err
+	}
+	for key, value := range overlay {
+		merged[key] = value
+	}
+	var result *config.Command
+	if err := unmarshalGeneric(merged, &result); err != nil {
+		return nil, fmt.Errorf("%s: %w", f.path, err)
+	}
+	return result, nil
+}
+
+// errorAt locates err at the start of snippet
+func (f *commandFile) errorAt(snippet *markjson.Snippet, err error) error {
+	return &ParseError{File: f.path, Line: snippet.Start.Line, Column: snippet.Start.Column, Err: err}
+}
+
+// settingsOf returns the settings of cmd, the inverse of apply
+func settingsOf(cmd *config.Command) *commandSettings {
+	return &commandSettings{
+		Name:                cmd.Name,
+		Aliases:             cmd.Aliases,
+		Output:              cmd.Output,
+		Groups:              cmd.Groups,
+		PersistentPlacement: cmd.PersistentPlacement,
+		Order:               cmd.Order,
+		Lifecycle:           cmd.Lifecycle,
+	}
+}
+
+// ParseError is a syntax error in a data block of a schema
+// file, located in the markdown file
+type ParseError struct {
+	File   string
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %v", e.File, e.Line, e.Column, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+var yamlLineRegex = regexp.MustCompile(`^(?:yaml: )?line (\d+):`)
+
+// unmarshalSnippet decodes a json or yaml code block into v,
+// syntax errors are returned as *ParseError located in file
+func unmarshalSnippet(file string, snippet *markjson.Snippet, v interface{}) error {
+	format := FormatOfLanguage(snippet.Language)
+	err := Unmarshal([]byte(snippet.Content), format, v)
+	if err == nil {
+		return nil
+	}
+	// offset is the byte offset of the error in the content
+	offset := -1
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case format != FormatJSON && format != "":
+		// json errors of converted yaml are not located
+		if m := yamlLineRegex.FindStringSubmatch(yamlErrorMessage(err)); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			offset = lineOffset(snippet.Content, line)
+		}
+	case errors.As(err, &syntaxErr):
+		// Offset is after the invalid character
+		offset = int(syntaxErr.Offset) - 1
+	case errors.As(err, &typeErr):
+		offset = int(typeErr.Offset) - 1
+	}
+	if offset < 0 {
+		return err
+	}
+	pos := snippet.PositionOf(offset)
+	return &ParseError{File: file, Line: pos.Line, Column: pos.Column, Err: err}
+}
+
+// yamlErrorMessage returns the first message of a yaml error,
+// a *yaml.TypeError lists one message per line
+func yamlErrorMessage(err error) string {
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) && len(typeErr.Errors) > 0 {
+		return typeErr.Errors[0]
+	}
+	return err.Error()
+}
+
+// lineOffset returns the byte offset of the 1-based line in content
+func lineOffset(content string, line int) int {
+	offset := 0
+	for i := 1; i < line; i++ {
+		idx := strings.Index(content[offset:], "\n")
+		if idx < 0 {
+			break
+		}
+		offset += idx + 1
+	}
+	return offset
+}
+
+// findDataSnippet finds the json or yaml code block of a section
+func findDataSnippet(snippets markjson.Snippets) *markjson.Snippet {
+	return snippets.FindLanguage("json", "yaml", "yml")
+}
+
 // cleanupDescription normalizes whitespace in a description string
 func cleanupDescription(desc string) string {
 	// Replace multiple whitespace characters (including newlines) with single spaces
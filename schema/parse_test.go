@@ -1,8 +1,11 @@
 package schema
 
 import (
+	"errors"
 	"strings"
 	"testing"
+
+	"github.com/xhd2015/cli2web/config"
 )
 
 // MockSchemaFile implements SchemaFile interface for testing
@@ -354,3 +357,279 @@ A complex command with multiple options and arguments.
 func contains(s, substr string) bool {
 	return strings.Contains(s, substr)
 }
+
+func TestParseCommandFromMarkdown_YAML(t *testing.T) {
+	content := `# Options
+` + "```yaml" + `
+# retried on network errors only
+- flags: --retries <n>
+  type: number
+  default: 3
+  description: |
+    how many times
+    to retry
+` + "```" + `
+
+# Settings
+` + "```yaml" + `
+name: yaml-cmd
+` + "```"
+
+	file := &MockSchemaFile{name: "test.md", content: content}
+	cmd, err := parseCommandFromMarkdown(file, "default-name")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cmd.Name != "yaml-cmd" {
+		t.Errorf("Expected name 'yaml-cmd', got '%s'", cmd.Name)
+	}
+	if len(cmd.Options) != 1 {
+		t.Fatalf("Expected 1 option, got %d", len(cmd.Options))
+	}
+	opt := cmd.Options[0]
+	if opt.Flags != "--retries <n>" || opt.Default != "3" || opt.Description != "how many times\nto retry" {
+		t.Errorf("Unexpected option %+v", opt)
+	}
+}
+
+func TestParseCommandFromMarkdown_Nested(t *testing.T) {
+	content := `# go
+## Description
+The go command
+
+## Options
+` + "```json" + `
+[{"flags": "-C <dir>", "type": "string", "persistent": true}]
+` + "```" + `
+
+## mod
+### Description
+Module maintenance
+
+### replace
+#### Arguments
+` + "```yaml" + `
+- name: module
+  type: string
+` + "```" + `
+
+#### Examples
+` + "```sh" + `
+# replace with a local copy
+go mod edit -replace a=../a
+` + "```" + `
+
+## version
+`
+
+	file := &MockSchemaFile{name: "go.md", content: content}
+	cmd, err := parseCommandFromMarkdown(file, "go")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cmd.Name != "go" || cmd.Description != "The go command" || len(cmd.Options) != 1 {
+		t.Errorf("Unexpected root command %+v", cmd)
+	}
+	var names []string
+	var walk func(cmds []*config.Command, prefix string)
+	walk = func(cmds []*config.Command, prefix string) {
+		for _, c := range cmds {
+			names = append(names, prefix+c.Name)
+			walk(c.Commands, prefix+c.Name+" ")
+		}
+	}
+	walk(cmd.Commands, "")
+	if got := strings.Join(names, ","); got != "mod,mod replace,version" {
+		t.Fatalf("Expected commands mod,mod replace,version, got %s", got)
+	}
+	mod := cmd.Commands[0]
+	if mod.Description != "Module maintenance" {
+		t.Errorf("Expected mod description, got %q", mod.Description)
+	}
+	replace := mod.Commands[0]
+	if len(replace.Arguments) != 1 || replace.Arguments[0].Name != "module" {
+		t.Errorf("Unexpected replace arguments %+v", replace.Arguments)
+	}
+	if len(replace.Examples) != 1 || replace.Examples[0].Usage != "# replace with a local copy\ngo mod edit -replace a=../a" {
+		t.Errorf("Unexpected replace examples %+v", replace.Examples)
+	}
+}
+
+func TestParseCommandFromMarkdown_NestedProse(t *testing.T) {
+	content := `# go
+## Description
+The go command
+
+## Notes
+The go command reads GOFLAGS.
+
+## version
+
+## mod
+See go help mod.
+
+### tidy
+`
+
+	file := &MockSchemaFile{name: "go.md", content: content}
+	cmd, err := parseCommandFromMarkdown(file, "go")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var names []string
+	for _, c := range cmd.Commands {
+		names = append(names, c.Name)
+	}
+	if got := strings.Join(names, ","); got != "version,mod" {
+		t.Fatalf("Expected commands version,mod, got %s", got)
+	}
+	if len(cmd.Commands[1].Commands) != 1 || cmd.Commands[1].Commands[0].Name != "tidy" {
+		t.Errorf("Expected mod tidy, got %+v", cmd.Commands[1].Commands)
+	}
+}
+
+func TestParseCommandFromMarkdown_FrontMatter(t *testing.T) {
+	content := `---
+title: Tag next
+name: tag-next
+description: Tag the next version
+hidden: true
+aliases: [tn, next]
+---
+# Settings
+` + "```json" + `
+{"aliases": ["t"]}
+` + "```"
+
+	file := &MockSchemaFile{name: "tag.md", content: content}
+	cmd, err := parseCommandFromMarkdown(file, "tag")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cmd.Name != "tag-next" || cmd.Description != "Tag the next version" || !cmd.Hidden {
+		t.Errorf("Unexpected command %+v", cmd)
+	}
+	// the settings section overrides front matter
+	if strings.Join(cmd.Aliases, ",") != "t" {
+		t.Errorf("Expected aliases [t], got %v", cmd.Aliases)
+	}
+
+	file = &MockSchemaFile{name: "tag.md", content: "+++\nname = \"tag-next\"\n+++\n"}
+	cmd, err = parseCommandFromMarkdown(file, "tag")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cmd.Name != "tag-next" {
+		t.Errorf("Expected name from TOML front matter, got %q", cmd.Name)
+	}
+}
+
+func TestParseCommandFromMarkdown_ErrorPosition(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name: "json syntax",
+			content: `---
+name: x
+---
+# Options
+` + "```json" + `
+[
+    {
+        "flags": "--invalid"
+        "missing_comma": true
+    }
+]
+` + "```",
+			expected: `invalid.md:9:9: invalid character '"' after object key:value pair`,
+		},
+		{
+			name: "json type",
+			content: `# Arguments
+` + "```json" + `
+[{"name": 1}]
+` + "```",
+			// the message varies by go version
+			expected: "invalid.md:3:11: json: cannot unmarshal number into Go struct field ",
+		},
+		{
+			name: "yaml in indented fence",
+			content: `# Settings
+- settings:
+  ` + "```yaml" + `
+  name: a
+  aliases: [b
+  ` + "```",
+			// yaml reports the line the flow sequence starts
+			expected: "invalid.md:4:3: yaml: line 1: did not find expected ',' or ']'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := &MockSchemaFile{name: "invalid.md", content: tt.content}
+			_, err := parseCommandFromMarkdown(file, "default-name")
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("Expected *ParseError, got %v", err)
+			}
+			if !strings.HasPrefix(parseErr.Error(), tt.expected) {
+				t.Errorf("Expected error %q, got %q", tt.expected, parseErr.Error())
+			}
+		})
+	}
+}
+
+func TestParseCommandFromMarkdown_Table(t *testing.T) {
+	content := `# Arguments
+| name | choices | description |
+|------|---------|-------------|
+| kind | major, minor | Kind of the version |
+
+# Options
+Options of tag.
+
+| Flags | Type | Default | Description | Persistent |
+|-------|------|---------|-------------|------------|
+| ` + "`--jobs <n>`" + ` | number | 1 | Number of jobs \| workers | |
+| ` + "`-v, --verbose`" + ` | boolean | | Verbose output | yes |
+`
+	file := &MockSchemaFile{name: "tag.md", content: content}
+	cmd, err := parseCommandFromMarkdown(file, "tag")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(cmd.Arguments) != 1 || cmd.Arguments[0].Name != "kind" || strings.Join(cmd.Arguments[0].Choices, ",") != "major,minor" {
+		t.Errorf("Unexpected arguments %+v", cmd.Arguments)
+	}
+	if len(cmd.Options) != 2 {
+		t.Fatalf("Expected 2 options, got %d", len(cmd.Options))
+	}
+	jobs, verbose := cmd.Options[0], cmd.Options[1]
+	if jobs.Flags != "--jobs <n>" || jobs.Type != "number" || jobs.Default != "1" || jobs.Description != "Number of jobs | workers" || jobs.Persistent {
+		t.Errorf("Unexpected option %+v", jobs)
+	}
+	if verbose.Flags != "-v, --verbose" || verbose.Type != "boolean" || !verbose.Persistent {
+		t.Errorf("Unexpected option %+v", verbose)
+	}
+
+	// a data snippet is still preferred over a table
+	file = &MockSchemaFile{name: "tag.md", content: content + "\n```json\n[{\"flags\": \"--dry-run\"}]\n```\n"}
+	cmd, err = parseCommandFromMarkdown(file, "tag")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(cmd.Options) != 1 || cmd.Options[0].Flags != "--dry-run" {
+		t.Errorf("Expected options from the json snippet, got %+v", cmd.Options)
+	}
+
+	// cell errors point at the row
+	file = &MockSchemaFile{name: "tag.md", content: strings.Replace(content, "| yes |", "| maybe |", 1)}
+	_, err = parseCommandFromMarkdown(file, "tag")
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Error() != `tag.md:12:1: column Persistent: expect true or false, got "maybe"` {
+		t.Errorf("Expected located table error, got %v", err)
+	}
+}
@@ -0,0 +1,419 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/xhd2015/cli2web/config"
+)
+
+const (
+	// refKey replaces an object by the value it points to,
+	// "#/definitions/auth", "common.json" or "common.json#/definitions/auth".
+	// Other keys of the object override those of the value, and an
+	// array value is spliced into the array holding the object.
+	refKey = "$ref"
+	// includeKey replaces an object by the schema of a json, yaml,
+	// toml or markdown file, or of a schema directory, as a command
+	includeKey = "include"
+	// definitionsKey holds the values that "#/definitions/<name>"
+	// points to, it is dropped from the schema
+	definitionsKey = "definitions"
+)
+
+// UnmarshalSchema decodes a json, yaml or toml schema like Unmarshal,
+// expanding $ref, definitions and include. Referenced files are read
+// from dir, file is the path of data in dir, empty when it is not
+// a file there, e.g. read from stdin.
+func UnmarshalSchema(data []byte, format string, dir SchemaDir, file string) (*config.Schema, error) {
+	generic, err := decodeGeneric(data, format)
+	if err != nil {
+		return nil, err
+	}
+	var s *config.Schema
+	if !hasRefs(generic, true) {
+		if err := Unmarshal(data, format, &s); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	resolved, err := newResolver(dir).resolve(withoutDefinitions(generic), &refDoc{file: file, root: generic, includes: true})
+	if err != nil {
+		return nil, err
+	}
+	if err := unmarshalGeneric(resolved, &s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// resolver expands $ref and include in decoded schema data. Files are
+// opened through root by slash separated paths, relative to the file
+// referring to them, and may not leave root.
+type resolver struct {
+	root SchemaDir
+	// active are the references being expanded, for cycle detection
+	active []string
+}
+
+func newResolver(root SchemaDir) *resolver {
+	return &resolver{root: root}
+}
+
+// refDoc is the document values are resolved in
+type refDoc struct {
+	// file is the path of the document relative to the root
+	file string
+	// root is what "#/..." points into
+	root interface{}
+	// includes tells if include is expanded, it is in schema files
+	// where objects are commands, and not in markdown data blocks
+	includes bool
+}
+
+// hasRefs tells if value holds a $ref, or an include if includes is set
+func hasRefs(value interface{}, includes bool) bool {
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if hasRefs(item, includes) {
+				return true
+			}
+		}
+	default:
+		obj, ok := toStringMap(value)
+		if !ok {
+			return false
+		}
+		if _, ok := obj[refKey]; ok {
+			return true
+		}
+		if _, ok := obj[includeKey]; ok && includes {
+			return true
+		}
+		for _, item := range obj {
+			if hasRefs(item, includes) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolve returns value with references expanded
+func (r *resolver) resolve(value interface{}, doc *refDoc) (interface{}, error) {
+	if list, ok := value.([]interface{}); ok {
+		result := make([]interface{}, 0, len(list))
+		for _, item := range list {
+			resolved, err := r.resolve(item, doc)
+			if err != nil {
+				return nil, err
+			}
+			if items, ok := resolved.([]interface{}); ok && isRefOnly(item) {
+				result = append(result, items...)
+				continue
+			}
+			result = append(result, resolved)
+		}
+		return result, nil
+	}
+	obj, ok := toStringMap(value)
+	if !ok {
+		return value, nil
+	}
+
+	var target interface{}
+	var expanded bool
+	if ref, ok := obj[refKey]; ok {
+		s, ok := ref.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a string, got %v", refKey, ref)
+		}
+		resolved, err := r.ref(s, doc)
+		if err != nil {
+			return nil, err
+		}
+		target, expanded = resolved, true
+	} else if include, ok := obj[includeKey]; ok && doc.includes {
+		s, ok := include.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a string, got %v", includeKey, include)
+		}
+		included, err := r.include(s, doc)
+		if err != nil {
+			return nil, err
+		}
+		target, expanded = included, true
+	}
+
+	result := make(map[string]interface{}, len(obj))
+	for key, item := range obj {
+		if expanded && (key == refKey || key == includeKey) {
+			continue
+		}
+		resolved, err := r.resolve(item, doc)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = resolved
+	}
+	if !expanded {
+		return result, nil
+	}
+	if len(result) == 0 {
+		return target, nil
+	}
+	base, ok := toStringMap(target)
+	if !ok {
+		return nil, fmt.Errorf("fields next to %s need an object, got %T", refKey, target)
+	}
+	merged := make(map[string]interface{}, len(base)+len(result))
+	for key, item := range base {
+		merged[key] = item
+	}
+	for key, item := range result {
+		merged[key] = item
+	}
+	return merged, nil
+}
+
+// withoutDefinitions returns value without its definitions, they are
+// only resolved where referred to
+func withoutDefinitions(value interface{}) interface{} {
+	obj, ok := toStringMap(value)
+	if !ok {
+		return value
+	}
+	if _, ok := obj[definitionsKey]; !ok {
+		return value
+	}
+	result := make(map[string]interface{}, len(obj))
+	for key, item := range obj {
+		if key != definitionsKey {
+			result[key] = item
+		}
+	}
+	return result
+}
+
+// isRefOnly tells if value is an object with nothing but a $ref
+func isRefOnly(value interface{}) bool {
+	obj, ok := toStringMap(value)
+	if !ok || len(obj) != 1 {
+		return false
+	}
+	_, ok = obj[refKey]
+	return ok
+}
+
+// ref returns the resolved value ref points to
+func (r *resolver) ref(ref string, doc *refDoc) (interface{}, error) {
+	file, pointer, _ := strings.Cut(ref, "#")
+	target := doc
+	if file != "" {
+		p, err := r.join(doc.file, file)
+		if err != nil {
+			return nil, fmt.Errorf("%s %q: %w", refKey, ref, err)
+		}
+		if strings.HasSuffix(p, ".md") {
+			return nil, fmt.Errorf("%s %q: markdown files are commands, use %s", refKey, ref, includeKey)
+		}
+		data, err := r.readData(p)
+		if err != nil {
+			return nil, fmt.Errorf("%s %q: %w", refKey, ref, err)
+		}
+		target = &refDoc{file: p, root: data, includes: doc.includes}
+	}
+
+	key := target.file + "#" + pointer
+	if err := r.enter(key); err != nil {
+		return nil, err
+	}
+	defer r.leave()
+	value, err := lookupPointer(target.root, pointer)
+	if err != nil {
+		return nil, fmt.Errorf("%s %q: %w", refKey, ref, err)
+	}
+	return r.resolve(value, target)
+}
+
+// include returns the command of the schema at file, relative
+// to doc, with its references resolved
+func (r *resolver) include(file string, doc *refDoc) (interface{}, error) {
+	p, err := r.join(doc.file, file)
+	if err != nil {
+		return nil, fmt.Errorf("%s %q: %w", includeKey, file, err)
+	}
+	if err := r.enter(p); err != nil {
+		return nil, err
+	}
+	defer r.leave()
+
+	dir, schemaFile, err := r.open(p)
+	if err != nil {
+		return nil, fmt.Errorf("%s %q: %w", includeKey, file, err)
+	}
+	var cmd *config.Command
+	switch {
+	case dir != nil:
+		cmd, err = r.parseDir(dir, p, nil, nil)
+	case strings.HasSuffix(p, ".md"):
+		_, name := splitOrderPrefix(strings.TrimSuffix(path.Base(p), ".md"))
+		cmd, err = parseCommandMarkdown(schemaFile, &commandFile{rel: p, refs: r}, name)
+	default:
+		var data interface{}
+		data, err = r.readData(p)
+		if err != nil {
+			break
+		}
+		var resolved interface{}
+		resolved, err = r.resolve(withoutDefinitions(data), &refDoc{file: p, root: data, includes: true})
+		if err != nil {
+			break
+		}
+		return resolved, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s %q: %w", includeKey, file, err)
+	}
+	return toGeneric(cmd)
+}
+
+// enter marks key as being expanded, an error if it already is
+func (r *resolver) enter(key string) error {
+	for i, active := range r.active {
+		if active == key {
+			cycle := append(append([]string(nil), r.active[i:]...), key)
+			return fmt.Errorf("reference cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	r.active = append(r.active, key)
+	return nil
+}
+
+func (r *resolver) leave() {
+	r.active = r.active[:len(r.active)-1]
+}
+
+// join returns the path of file referred to from the file from,
+// absolute paths start at the root
+func (r *resolver) join(from string, file string) (string, error) {
+	var p string
+	if strings.HasPrefix(file, "/") {
+		p = path.Clean(strings.TrimPrefix(file, "/"))
+	} else {
+		p = path.Join(path.Dir(from), file)
+	}
+	if p == ".." || strings.HasPrefix(p, "../") {
+		return "", fmt.Errorf("%s is outside of the schema directory", file)
+	}
+	return p, nil
+}
+
+// open finds the directory or file at p
+func (r *resolver) open(p string) (SchemaDir, SchemaFile, error) {
+	if r.root == nil {
+		return nil, nil, fmt.Errorf("no schema directory to read %s from", p)
+	}
+	dir := r.root
+	if p == "." {
+		return dir, nil, nil
+	}
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		dirs, err := dir.ListDirs()
+		if err != nil {
+			return nil, nil, err
+		}
+		var next SchemaDir
+		for _, d := range dirs {
+			if d.Name() == part {
+				next = d
+				break
+			}
+		}
+		if next != nil {
+			dir = next
+			continue
+		}
+		if i < len(parts)-1 {
+			return nil, nil, fmt.Errorf("%s not found", p)
+		}
+		files, err := dir.ListFiles()
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, f := range files {
+			if f.Name() == part {
+				return nil, f, nil
+			}
+		}
+		return nil, nil, fmt.Errorf("%s not found", p)
+	}
+	return dir, nil, nil
+}
+
+// readData decodes the json, yaml or toml file at p
+func (r *resolver) readData(p string) (interface{}, error) {
+	_, file, err := r.open(p)
+	if err != nil {
+		return nil, err
+	}
+	if file == nil {
+		return nil, fmt.Errorf("%s is a directory", p)
+	}
+	data, err := file.Read()
+	if err != nil {
+		return nil, err
+	}
+	generic, err := decodeGeneric(data, DetectFormat(p, data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+	return generic, nil
+}
+
+// lookupPointer returns the value at the JSON pointer in root
+func lookupPointer(root interface{}, pointer string) (interface{}, error) {
+	if pointer == "" || pointer == "/" {
+		return root, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid pointer %q", pointer)
+	}
+	value := root
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		if list, ok := value.([]interface{}); ok {
+			var i int
+			if _, err := fmt.Sscanf(token, "%d", &i); err != nil || i < 0 || i >= len(list) {
+				return nil, fmt.Errorf("%s not found", pointer)
+			}
+			value = list[i]
+			continue
+		}
+		obj, ok := toStringMap(value)
+		if !ok {
+			return nil, fmt.Errorf("%s not found", pointer)
+		}
+		if value, ok = obj[token]; !ok {
+			return nil, fmt.Errorf("%s not found", pointer)
+		}
+	}
+	return value, nil
+}
+
+// toGeneric converts v to maps, slices and scalars
+func toGeneric(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
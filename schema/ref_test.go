@@ -0,0 +1,188 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/xhd2015/cli2web/config"
+)
+
+func refTestFS() fstest.MapFS {
+	return fstest.MapFS{
+		"kool/kool.json": {Data: []byte(`{
+    "name": "kool",
+    "definitions": {
+        "output": [
+            {"flags": "--json", "type": "boolean"},
+            {"flags": "--color", "type": "boolean"}
+        ]
+    },
+    "commands": [
+        {
+            "name": "list",
+            "options": [
+                {"$ref": "#/definitions/output"},
+                {"$ref": "common.yaml#/definitions/verbose", "description": "Chatty"}
+            ]
+        },
+        {"include": "tools/fmt.yaml"},
+        {"include": "git", "description": "Git, included"}
+    ]
+}`)},
+		"kool/common.yaml": {Data: []byte(`definitions:
+  verbose:
+    flags: -v, --verbose
+    type: boolean
+    description: Verbose output
+`)},
+		"kool/tools/fmt.yaml": {Data: []byte(`name: fmt
+options:
+  - $ref: ../common.yaml#/definitions/verbose
+`)},
+		"kool/git/_index.md":   {Data: []byte("# Description\nGit\n")},
+		"kool/git/status.md":   {Data: []byte("# Description\nStatus\n")},
+		"kool/cycle.json":      {Data: []byte(`{"definitions": {"a": {"$ref": "#/definitions/b"}, "b": {"$ref": "#/definitions/a"}}, "options": [{"$ref": "#/definitions/a"}]}`)},
+		"kool/outside.json":    {Data: []byte(`{"options": [{"$ref": "../secret.json"}]}`)},
+		"kool/include-md.json": {Data: []byte(`{"name": "kool", "commands": [{"include": "git/status.md"}]}`)},
+	}
+}
+
+func unmarshalRefTestSchema(t *testing.T, file string) (*config.Schema, error) {
+	t.Helper()
+	fsys := refTestFS()
+	data := fsys["kool/"+file].Data
+	return UnmarshalSchema(data, FormatJSON, NewGenericFSSchemaDir(fsys, "kool"), file)
+}
+
+func TestUnmarshalSchema_Refs(t *testing.T) {
+	s, err := unmarshalRefTestSchema(t, "kool.json")
+	if err != nil {
+		t.Fatalf("UnmarshalSchema() error = %v", err)
+	}
+	if len(s.Commands) != 3 {
+		t.Fatalf("expect 3 commands, got %d", len(s.Commands))
+	}
+
+	list := s.Commands[0]
+	var flags []string
+	for _, option := range list.Options {
+		flags = append(flags, option.Flags)
+	}
+	// the definition list is spliced into the options
+	if got, expected := strings.Join(flags, " "), "--json --color -v, --verbose"; got != expected {
+		t.Errorf("list options = %q, expected %q", got, expected)
+	}
+	if got := list.Options[2].Description; got != "Chatty" {
+		t.Errorf("expect fields next to $ref to override, got description %q", got)
+	}
+
+	fmtCmd := s.Commands[1]
+	if fmtCmd.Name != "fmt" || len(fmtCmd.Options) != 1 || fmtCmd.Options[0].Description != "Verbose output" {
+		t.Errorf("unexpected included fmt command %+v", fmtCmd)
+	}
+
+	git := s.Commands[2]
+	if git.Name != "git" || git.Description != "Git, included" {
+		t.Errorf("unexpected included git command %+v", git)
+	}
+	if len(git.Commands) != 1 || git.Commands[0].Name != "status" {
+		t.Errorf("expect git status from the included directory, got %+v", git.Commands)
+	}
+}
+
+func TestUnmarshalSchema_IncludeMarkdown(t *testing.T) {
+	s, err := unmarshalRefTestSchema(t, "include-md.json")
+	if err != nil {
+		t.Fatalf("UnmarshalSchema() error = %v", err)
+	}
+	if len(s.Commands) != 1 || s.Commands[0].Name != "status" || s.Commands[0].Description != "Status" {
+		t.Errorf("unexpected included markdown command %+v", s.Commands)
+	}
+}
+
+func TestUnmarshalSchema_RefErrors(t *testing.T) {
+	tests := []struct {
+		file     string
+		expected string
+	}{
+		{"cycle.json", "reference cycle: cycle.json#/definitions/a -> cycle.json#/definitions/b -> cycle.json#/definitions/a"},
+		{"outside.json", "../secret.json is outside of the schema directory"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			_, err := unmarshalRefTestSchema(t, tt.file)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expect error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestUnmarshalSchema_IncludeCycle(t *testing.T) {
+	fsys := fstest.MapFS{
+		"kool/a.json": {Data: []byte(`{"name": "a", "commands": [{"include": "b.json"}]}`)},
+		"kool/b.json": {Data: []byte(`{"name": "b", "commands": [{"include": "a.json"}]}`)},
+	}
+	_, err := UnmarshalSchema(fsys["kool/a.json"].Data, FormatJSON, NewGenericFSSchemaDir(fsys, "kool"), "a.json")
+	if err == nil || !strings.Contains(err.Error(), "reference cycle: b.json -> a.json -> b.json") {
+		t.Errorf("expect include cycle error, got %v", err)
+	}
+}
+
+func TestParseSchemaFromFS_Definitions(t *testing.T) {
+	fsys := fstest.MapFS{
+		"kool/_index.md": {Data: []byte("# Definitions\n```yaml\n" +
+			"verbose:\n  flags: -v, --verbose\n  type: boolean\n" +
+			"common:\n  - $ref: '#/definitions/verbose'\n  - flags: --dry-run\n    type: boolean\n" +
+			"```\n")},
+		"kool/git/_index.md": {Data: []byte("# Definitions\n```json\n{\"remote\": {\"flags\": \"--remote <name>\"}}\n```\n" +
+			"# Options\n```json\n[{\"$ref\": \"#/definitions/remote\"}]\n```\n")},
+		"kool/git/push.md": {Data: []byte("# Options\n```json\n" +
+			`[{"$ref": "#/definitions/common"}, {"$ref": "#/definitions/remote", "description": "Remote to push to"}]` +
+			"\n```\n")},
+		"kool/tools.md":          {Data: []byte("# Settings\n```json\n{\"include\": \"shared/tools.json\", \"description\": \"Tools\"}\n```\n")},
+		"kool/shared/tools.json": {Data: []byte(`{"name": "ignored", "description": "Shared tools", "commands": [{"name": "lint"}]}`)},
+	}
+	s, err := ParseSchemaFromFS(fsys, "kool")
+	if err != nil {
+		t.Fatalf("ParseSchemaFromFS failed: %v", err)
+	}
+	commands := make(map[string]*config.Command)
+	for _, cmd := range s.Commands {
+		commands[cmd.Name] = cmd
+	}
+
+	git := commands["git"]
+	if git == nil || len(git.Options) != 1 || git.Options[0].Flags != "--remote <name>" {
+		t.Fatalf("unexpected git command %+v", git)
+	}
+	push := git.Commands[0]
+	var flags []string
+	for _, option := range push.Options {
+		flags = append(flags, option.Flags)
+	}
+	// definitions of the index files above apply
+	if got, expected := strings.Join(flags, " "), "-v, --verbose --dry-run --remote <name>"; got != expected {
+		t.Errorf("push options = %q, expected %q", got, expected)
+	}
+	if push.Options[2].Description != "Remote to push to" {
+		t.Errorf("unexpected push --remote %+v", push.Options[2])
+	}
+
+	tools := commands["tools"]
+	if tools == nil || tools.Description != "Tools" || len(tools.Commands) != 1 || tools.Commands[0].Name != "lint" {
+		t.Errorf("unexpected included tools command %+v", tools)
+	}
+	if tools != nil && tools.Name != "tools" {
+		t.Errorf("expect the file to name the included command, got %s", tools.Name)
+	}
+}
+
+func TestParseCommandFromMarkdown_RefErrorLocated(t *testing.T) {
+	file := &MockSchemaFile{name: "tag.md", content: "# Description\nTag\n\n# Options\n```json\n[{\"$ref\": \"#/definitions/missing\"}]\n```\n"}
+	_, err := parseCommandFromMarkdown(file, "tag")
+	if err == nil || !strings.Contains(err.Error(), `tag.md:6:1: $ref "#/definitions/missing": /definitions/missing not found`) {
+		t.Errorf("expect located ref error, got %v", err)
+	}
+}
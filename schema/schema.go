@@ -2,6 +2,10 @@ package schema
 
 import (
 	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/xhd2015/cli2web/config"
@@ -18,147 +22,278 @@ type SchemaFile interface {
 	Read() ([]byte, error)
 }
 
+// SchemaPath is optionally implemented by a SchemaFile to name
+// it in errors by its full path, e.g. schema-example/go/go.md
+type SchemaPath interface {
+	Path() string
+}
+
+// filePath returns the path of file for errors
+func filePath(file SchemaFile) string {
+	if p, ok := file.(SchemaPath); ok {
+		return p.Path()
+	}
+	return file.Name()
+}
+
 // ParseSchema converts a directory-based schema to a unified config.Schema
 func ParseSchema(rootDir SchemaDir) (*config.Schema, error) {
-	// Determine the root command name based on the new logic
-	rootName, rootSchemaFile, err := determineCommandName(rootDir)
+	return parseSchema(rootDir, nil)
+}
+
+// commandSource is the markdown file, relative to the root
+// directory, a command is parsed from. For a directory without
+// markdown file, file is the directory and content is empty.
+type commandSource struct {
+	file    string
+	content string
+}
+
+// sourceMap records where commands come from, for diagnostics
+type sourceMap map[*config.Command]*commandSource
+
+func parseSchema(rootDir SchemaDir, sources sourceMap) (*config.Schema, error) {
+	return newResolver(rootDir).parseDir(rootDir, "", nil, sources)
+}
+
+// parseDir parses the schema of dir, relDir is its path relative to
+// the root directory and definitions those of the directories above.
+// Without index file, a single .md file of dir is the root command.
+func (r *resolver) parseDir(dir SchemaDir, relDir string, definitions map[string]interface{}, sources sourceMap) (*config.Schema, error) {
+	index, leaves, err := commandFiles(dir)
 	if err != nil {
-		return nil, fmt.Errorf("failed to determine root name: %w", err)
+		return nil, err
+	}
+	_, rootName := splitOrderPrefix(dir.Name())
+	if index == nil && len(leaves) == 1 {
+		// the layout without index files: the only .md file of the
+		// root directory is the root command, named after the file
+		index = leaves[0]
+		leaves = nil
+		_, rootName = splitOrderPrefix(strings.TrimSuffix(index.Name(), ".md"))
 	}
-	_ = rootSchemaFile
-
 	schema := &config.Schema{
-		Name:     rootName,
-		Commands: []*config.Command{},
+		Name: rootName,
+	}
+	if index != nil {
+		schema, definitions, err = r.parseCommandFile(index, path.Join(relDir, index.Name()), rootName, definitions, sources)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse root command: %w", err)
+		}
 	}
 
 	// Parse root directory
-	commands, err := parseCommands(rootDir)
+	commands, err := r.parseCommands(dir, relDir, leaves, definitions, sources)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse root commands: %w", err)
 	}
 
-	schema.Commands = commands
+	// commands nested in the root file come first
+	schema.Commands = append(schema.Commands, commands...)
 	return schema, nil
 }
 
-// determineCommandName determines the command name based on the new logic:
-// - If there is a single .md file, use that command's name
-// - If multiple .md files, prefer the one matching the directory name
-// - If none matches, select the first one sorted
-// - If no .md files, fall back to directory name
-func determineCommandName(dir SchemaDir) (string, SchemaFile, error) {
+// indexFiles describe the command of the directory they are in:
+// _index.md comes before <dir>.md, README.md after it so that a
+// readme next to <dir>.md of the layout without index files is not
+// read as the command
+var indexFiles = []string{"_index.md", "README.md"}
+
+// commandFiles splits the markdown files of dir into the index, the
+// file describing the command of dir itself, and the files of leaf
+// commands. The index is the first of _index.md, <dir>.md and
+// README.md, the others of them are ignored. <dir> may be written
+// with or without the order prefix of the directory.
+func commandFiles(dir SchemaDir) (index SchemaFile, leaves []SchemaFile, err error) {
 	files, err := dir.ListFiles()
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to list files in directory: %w", err)
+		return nil, nil, fmt.Errorf("failed to list files in directory: %w", err)
 	}
-
-	// Find all .md files
-	var mdFiles []SchemaFile
+	candidates := indexCandidates(dir.Name())
+	rank := len(candidates)
 	for _, file := range files {
-		if strings.HasSuffix(file.Name(), ".md") {
-			mdFiles = append(mdFiles, file)
+		if !strings.HasSuffix(file.Name(), ".md") {
+			continue
 		}
-	}
-
-	// If no .md files, fall back to directory name
-	if len(mdFiles) == 0 {
-		return dir.Name(), nil, nil
-	}
-
-	// If single .md file, use its command name
-	if len(mdFiles) == 1 {
-		cmd, err := parseCommandFromMarkdown(mdFiles[0], strings.TrimSuffix(mdFiles[0].Name(), ".md"))
-		if err != nil {
-			return "", nil, fmt.Errorf("failed to parse command from %s: %w", mdFiles[0].Name(), err)
-		}
-		return cmd.Name, mdFiles[0], nil
-	}
-
-	// Multiple .md files: prefer the one matching directory name
-	dirName := dir.Name()
-	for _, file := range mdFiles {
-		cmdName := strings.TrimSuffix(file.Name(), ".md")
-		if cmdName == dirName {
-			cmd, err := parseCommandFromMarkdown(file, cmdName)
-			if err != nil {
-				return "", nil, fmt.Errorf("failed to parse command from %s: %w", file.Name(), err)
+		if i := indexOfString(candidates, file.Name()); i >= 0 {
+			if i < rank {
+				index, rank = file, i
 			}
-			return cmd.Name, file, nil
+			continue
 		}
+		leaves = append(leaves, file)
 	}
+	return index, leaves, nil
+}
 
-	// No match with directory name, select first one sorted
-	var fileNames []string
-	for _, file := range mdFiles {
-		fileNames = append(fileNames, file.Name())
-	}
-
-	// Sort the file names
-	for i := 0; i < len(fileNames); i++ {
-		for j := i + 1; j < len(fileNames); j++ {
-			if fileNames[i] > fileNames[j] {
-				fileNames[i], fileNames[j] = fileNames[j], fileNames[i]
-			}
-		}
+// indexCandidates returns the names of the files that may describe
+// the command of the directory dirName, in order of precedence
+func indexCandidates(dirName string) []string {
+	candidates := []string{indexFiles[0], dirName + ".md"}
+	if _, name := splitOrderPrefix(dirName); name != dirName {
+		candidates = append(candidates, name+".md")
 	}
+	return append(candidates, indexFiles[1:]...)
+}
 
-	// Find the first file and parse it
-	firstName := fileNames[0]
-	var firstFile SchemaFile
-	for _, file := range mdFiles {
-		if file.Name() == firstName {
-			firstFile = file
-			break
+func indexOfString(list []string, s string) int {
+	for i, e := range list {
+		if e == s {
+			return i
 		}
 	}
+	return -1
+}
 
-	cmd, err := parseCommandFromMarkdown(firstFile, strings.TrimSuffix(firstName, ".md"))
+// parseCommandFile parses the command of file, relFile is its path
+// relative to the root directory. It returns definitions with those
+// of the file added.
+func (r *resolver) parseCommandFile(file SchemaFile, relFile string, defaultName string, definitions map[string]interface{}, sources sourceMap) (*config.Command, map[string]interface{}, error) {
+	f := &commandFile{rel: relFile, refs: r, definitions: definitions}
+	cmd, err := parseCommandMarkdown(file, f, defaultName)
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to parse command from %s: %w", firstName, err)
+		return nil, nil, err
 	}
-	return cmd.Name, firstFile, nil
+	if sources != nil {
+		content, err := file.Read()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read file %s: %w", file.Name(), err)
+		}
+		sources[cmd] = &commandSource{file: relFile, content: string(content)}
+	}
+	return cmd, f.definitions, nil
 }
 
-// parseCommands recursively parses commands from a directory
-func parseCommands(dir SchemaDir) ([]*config.Command, error) {
-	var commands []*config.Command
-
+// parseCommands recursively parses the commands of a directory: a leaf
+// command for each of leaves, and a command for each subdirectory.
+// A <name>.md next to the directory <name> describes its command if
+// the directory has no index. Commands are named after their file
+// or directory without order prefix, see sortEntries for the order.
+// relDir is the path of dir relative to the root directory,
+// definitions are those of the directory.
+func (r *resolver) parseCommands(dir SchemaDir, relDir string, leaves []SchemaFile, definitions map[string]interface{}, sources sourceMap) ([]*config.Command, error) {
 	dirs, err := dir.ListDirs()
 	if err != nil {
 		return nil, fmt.Errorf("failed to list directories in %s: %w", dir.Name(), err)
 	}
 
+	dirNames := make(map[string]bool, len(dirs))
+	dirFiles := make(map[string]SchemaFile)
+	var entries []*dirEntry
 	for _, subDir := range dirs {
-		cmdName, schemaFile, err := determineCommandName(subDir)
+		entries = append(entries, newDirEntry(subDir.Name(), nil, subDir))
+		dirNames[entries[len(entries)-1].name] = true
+	}
+	for _, file := range leaves {
+		e := newDirEntry(strings.TrimSuffix(file.Name(), ".md"), file, nil)
+		if dirNames[e.name] {
+			dirFiles[e.name] = file
+			continue
+		}
+		entries = append(entries, e)
+	}
+	sortEntries(entries)
+
+	var commands []*config.Command
+	for _, e := range entries {
+		if e.file != nil {
+			cmd, _, err := r.parseCommandFile(e.file, path.Join(relDir, e.file.Name()), e.name, definitions, sources)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse command %s: %w", e.name, err)
+			}
+			commands = append(commands, cmd)
+			continue
+		}
+
+		subRelDir := path.Join(relDir, e.dir.Name())
+		index, subLeaves, err := commandFiles(e.dir)
 		if err != nil {
-			return nil, fmt.Errorf("failed to determine command name for subdirectory %s: %w", subDir.Name(), err)
+			return nil, fmt.Errorf("failed to list command files of %s: %w", subRelDir, err)
+		}
+		relIndex := ""
+		if index != nil {
+			relIndex = path.Join(subRelDir, index.Name())
+		}
+		if file := dirFiles[e.name]; file != nil {
+			if index != nil {
+				return nil, fmt.Errorf("command %s is described by both %s and %s", e.name, path.Join(relDir, file.Name()), relIndex)
+			}
+			index, relIndex = file, path.Join(relDir, file.Name())
 		}
 
 		var cmd *config.Command
-		// If we found a matching file, parse it as a command
-		if schemaFile != nil {
-			var err error
-			cmd, err = parseCommandFromMarkdown(schemaFile, cmdName)
+		subDefinitions := definitions
+		if index != nil {
+			cmd, subDefinitions, err = r.parseCommandFile(index, relIndex, e.name, definitions, sources)
 			if err != nil {
-				return nil, fmt.Errorf("failed to parse command %s from subdirectory: %w", cmdName, err)
+				return nil, fmt.Errorf("failed to parse command %s from subdirectory: %w", e.name, err)
 			}
 		} else {
 			cmd = &config.Command{
-				Name: cmdName,
+				Name: e.name,
+			}
+			if sources != nil {
+				sources[cmd] = &commandSource{file: subRelDir}
 			}
 		}
 
 		// Recursively parse any subcommands
-		subCommands, err := parseCommands(subDir)
+		subCommands, err := r.parseCommands(e.dir, subRelDir, subLeaves, subDefinitions, sources)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse subcommands for %s: %w", cmdName, err)
+			return nil, fmt.Errorf("failed to parse subcommands for %s: %w", cmd.Name, err)
 		}
-		cmd.Commands = subCommands
+		cmd.Commands = append(cmd.Commands, subCommands...)
 
 		commands = append(commands, cmd)
-
 	}
 
 	return commands, nil
 }
+
+// dirEntry is a subdirectory or leaf command file
+type dirEntry struct {
+	// base is the file name without .md, name is base
+	// without the order prefix
+	base   string
+	name   string
+	prefix int
+	file   SchemaFile
+	dir    SchemaDir
+}
+
+func newDirEntry(base string, file SchemaFile, dir SchemaDir) *dirEntry {
+	prefix, name := splitOrderPrefix(base)
+	return &dirEntry{base: base, name: name, prefix: prefix, file: file, dir: dir}
+}
+
+// sortEntries puts entries with an order prefix first, by the
+// number, then the others by file name. The order setting of
+// commands is applied later where they are listed.
+func sortEntries(entries []*dirEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if (a.prefix >= 0) != (b.prefix >= 0) {
+			return a.prefix >= 0
+		}
+		if a.prefix != b.prefix {
+			return a.prefix < b.prefix
+		}
+		return a.base < b.base
+	})
+}
+
+var orderPrefixRegex = regexp.MustCompile(`^(\d+)[-_](.+)$`)
+
+// splitOrderPrefix splits a name like 01-git into 1 and git,
+// prefix is -1 without order prefix
+func splitOrderPrefix(base string) (prefix int, name string) {
+	m := orderPrefixRegex.FindStringSubmatch(base)
+	if m == nil {
+		return -1, base
+	}
+	prefix, err := strconv.Atoi(m[1])
+	if err != nil {
+		return -1, base
+	}
+	return prefix, m[2]
+}
@@ -0,0 +1,160 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/xhd2015/cli2web/config"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// FromStruct builds options from the fields of the struct v points to.
+// Fields are described by tags:
+//
+//	flag:"name"      flag name, defaults to the kebab-cased field name, "-" skips the field
+//	short:"n"        one letter alias
+//	desc:"..."       description
+//	choices:"a,b"    allowed values
+//	default:"..."    default value, defaults to the field's current value
+//
+// bool fields become checkboxes, numeric fields numbers, string,
+// time.Duration and []string fields strings. Embedded structs
+// contribute their fields.
+func FromStruct(v interface{}) (*config.Command, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("requires struct or pointer to struct, got %T", v)
+	}
+	options, err := structOptions(rv)
+	if err != nil {
+		return nil, err
+	}
+	return &config.Command{Options: options}, nil
+}
+
+func structOptions(rv reflect.Value) ([]*config.Option, error) {
+	var options []*config.Option
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		value := rv.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			embedded, err := structOptions(value)
+			if err != nil {
+				return nil, err
+			}
+			options = append(options, embedded...)
+			continue
+		}
+		if field.PkgPath != "" {
+			// unexported
+			continue
+		}
+		name := field.Tag.Get("flag")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = kebabCase(field.Name)
+		}
+		opt, err := fieldOption(field, value, name)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		options = append(options, opt)
+	}
+	return options, nil
+}
+
+func fieldOption(field reflect.StructField, value reflect.Value, name string) (*config.Option, error) {
+	opt := &config.Option{
+		Description: field.Tag.Get("desc"),
+	}
+	placeholder := "string"
+	switch {
+	case field.Type == durationType:
+		opt.Type = config.TypeString
+		placeholder = "duration"
+	default:
+		switch field.Type.Kind() {
+		case reflect.Bool:
+			opt.Type = config.TypeBoolean
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			opt.Type = config.TypeNumber
+			placeholder = "int"
+		case reflect.Float32, reflect.Float64:
+			opt.Type = config.TypeNumber
+			placeholder = "float"
+		case reflect.String:
+			opt.Type = config.TypeString
+		case reflect.Slice:
+			if field.Type.Elem().Kind() != reflect.String {
+				return nil, fmt.Errorf("unsupported type %s", field.Type)
+			}
+			opt.Type = config.TypeString
+			placeholder = "strings"
+		default:
+			return nil, fmt.Errorf("unsupported type %s", field.Type)
+		}
+	}
+
+	flags := "--" + name
+	if short := field.Tag.Get("short"); short != "" {
+		flags = "-" + short + ", " + flags
+	}
+	if opt.Type != config.TypeBoolean {
+		flags += " <" + placeholder + ">"
+	}
+	opt.Flags = flags
+
+	if choices := field.Tag.Get("choices"); choices != "" {
+		for _, choice := range strings.Split(choices, ",") {
+			opt.Choices = append(opt.Choices, strings.TrimSpace(choice))
+		}
+	}
+	if def, ok := field.Tag.Lookup("default"); ok {
+		opt.Default = def
+	} else if !value.IsZero() {
+		opt.Default = formatValue(value)
+	}
+	return opt, nil
+}
+
+func formatValue(value reflect.Value) string {
+	if value.Kind() == reflect.Slice {
+		items := make([]string, value.Len())
+		for i := range items {
+			items[i] = value.Index(i).String()
+		}
+		return strings.Join(items, ",")
+	}
+	if value.Type() == durationType {
+		return time.Duration(value.Int()).String()
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+// kebabCase converts DryRun to dry-run
+func kebabCase(name string) string {
+	var sb strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// keep acronyms like URL together
+			if i > 0 && (!unicode.IsUpper(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				sb.WriteByte('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
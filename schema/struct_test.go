@@ -0,0 +1,58 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type commonOptions struct {
+	Verbose bool `short:"v" desc:"verbose output"`
+}
+
+type tagOptions struct {
+	commonOptions
+	Format  string        `flag:"format" short:"f" desc:"output format" choices:"text, json"`
+	Retries int           `desc:"retry times" default:"3"`
+	Timeout time.Duration `desc:"how long to wait"`
+	Labels  []string      `desc:"labels"`
+	DryRun  bool
+	BaseURL string
+	Ignored string `flag:"-"`
+	unused  string
+}
+
+func TestFromStruct(t *testing.T) {
+	opts := &tagOptions{
+		Format:  "text",
+		Timeout: 2 * time.Second,
+		Labels:  []string{"a", "b"},
+	}
+	cmd, err := FromStruct(opts)
+	if err != nil {
+		t.Fatalf("FromStruct() error = %v", err)
+	}
+	var actual []string
+	for _, opt := range cmd.Options {
+		actual = append(actual, strings.Join([]string{opt.Flags, opt.Type, opt.Default, opt.Description, strings.Join(opt.Choices, "/")}, "|"))
+	}
+	expected := []string{
+		"-v, --verbose|boolean||verbose output|",
+		"-f, --format <string>|string|text|output format|text/json",
+		"--retries <int>|number|3|retry times|",
+		"--timeout <duration>|string|2s|how long to wait|",
+		"--labels <strings>|string|a,b|labels|",
+		"--dry-run|boolean|||",
+		"--base-url <string>|string|||",
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Options =\n  %s\nexpected\n  %s", strings.Join(actual, "\n  "), strings.Join(expected, "\n  "))
+	}
+
+	if _, err := FromStruct("x"); err == nil {
+		t.Errorf("Expected error for non-struct")
+	}
+	if _, err := FromStruct(&struct{ C chan int }{}); err == nil {
+		t.Errorf("Expected error for unsupported field type")
+	}
+}
@@ -0,0 +1,113 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/xhd2015/cli2web/markjson"
+)
+
+// tableData converts the rows of a markdown table to objects of
+// the struct elem. Columns are matched to json fields ignoring case,
+// spaces, '-' and '_'. Cells are converted by the field type: lists
+// are comma separated, booleans are true/yes/x or false/no. Empty
+// cells are left out, and the backticks of `--flag` are dropped.
+// Unmatched columns are kept as written.
+func tableData(table *markjson.Snippet, elem reflect.Type) ([]interface{}, error) {
+	for elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	fields := jsonFields(elem)
+	normalized := make(map[string]string, len(fields))
+	for name := range fields {
+		normalized[normalizeColumn(name)] = name
+	}
+
+	rows := make([]interface{}, 0, len(table.Rows))
+	for i, row := range table.Rows {
+		obj := make(map[string]interface{}, len(row))
+		for _, column := range table.Columns {
+			cell := unquoteCell(row[column])
+			if cell == "" {
+				continue
+			}
+			name, ok := normalized[normalizeColumn(column)]
+			if !ok {
+				obj[column] = cell
+				continue
+			}
+			value, err := cellValue(cell, fields[name])
+			if err != nil {
+				return nil, &ParseError{Line: tableRowLine(table, i), Column: table.Start.Column, Err: fmt.Errorf("column %s: %w", column, err)}
+			}
+			obj[name] = value
+		}
+		rows = append(rows, obj)
+	}
+	return rows, nil
+}
+
+// tableRowLine is the line of the i-th row, after the header
+// and the delimiter row
+func tableRowLine(table *markjson.Snippet, i int) int {
+	return table.Start.Line + 2 + i
+}
+
+func normalizeColumn(name string) string {
+	return strings.NewReplacer(" ", "", "-", "", "_", "").Replace(strings.ToLower(name))
+}
+
+// unquoteCell drops the backticks around inline code
+func unquoteCell(cell string) string {
+	cell = strings.TrimSpace(cell)
+	if len(cell) >= 2 && strings.HasPrefix(cell, "`") && strings.HasSuffix(cell, "`") {
+		return strings.TrimSpace(cell[1 : len(cell)-1])
+	}
+	return cell
+}
+
+func cellValue(cell string, t reflect.Type) (interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		switch strings.ToLower(cell) {
+		case "true", "yes", "x":
+			return true, nil
+		case "false", "no":
+			return false, nil
+		}
+		return nil, fmt.Errorf("expect true or false, got %q", cell)
+	case reflect.Slice:
+		var items []interface{}
+		for _, item := range strings.Split(cell, ",") {
+			if item = unquoteCell(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.Atoi(cell)
+		if err != nil {
+			return nil, fmt.Errorf("expect a number, got %q", cell)
+		}
+		return n, nil
+	}
+	return cell, nil
+}
+
+// unmarshalTable decodes the rows of a table into v, a pointer
+// to a slice, errors are located in file
+func unmarshalTable(file string, table *markjson.Snippet, v interface{}) error {
+	rows, err := tableData(table, reflect.TypeOf(v).Elem().Elem())
+	if err != nil {
+		if parseErr, ok := err.(*ParseError); ok {
+			parseErr.File = file
+		}
+		return err
+	}
+	return unmarshalGeneric(rows, v)
+}
@@ -0,0 +1,259 @@
+package schema
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xhd2015/cli2web/config"
+)
+
+// Diagnostic is a problem found in a schema
+type Diagnostic struct {
+	// Pointer is the JSON pointer of the offending item,
+	// e.g. "/commands/0/options/1"
+	Pointer string
+	// File and Line locate the item in the source, Line
+	// is 0 when unknown
+	File string
+	Line int
+	// Path names the offending item, e.g. "kool git tag-next --push"
+	Path    string
+	Message string
+}
+
+func (d *Diagnostic) String() string {
+	var parts []string
+	if d.File != "" {
+		loc := d.File
+		if d.Line > 0 {
+			loc += ":" + strconv.Itoa(d.Line)
+		}
+		parts = append(parts, loc)
+	}
+	if d.Line == 0 && d.Pointer != "" {
+		parts = append(parts, d.Pointer)
+	}
+	if d.Path != "" {
+		parts = append(parts, d.Path)
+	}
+	parts = append(parts, d.Message)
+	return strings.Join(parts, ": ")
+}
+
+// reservedNames are top level paths served by the web interface
+var reservedNames = map[string]bool{
+	"ws": true,
+}
+
+// urlSafeNameRegex matches names that can be used as URL path segments as is
+var urlSafeNameRegex = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`)
+
+// Validate checks a schema for problems that do not prevent
+// it from being loaded. Diagnostics carry JSON pointers, use
+// ValidateData or ValidateDir to also locate them in the source.
+func Validate(s *config.Schema) []*Diagnostic {
+	v := &validator{root: s}
+	v.validateCommand([]*config.Command{s}, nil, "")
+	return v.diagnostics
+}
+
+type validator struct {
+	root        *config.Schema
+	diagnostics []*Diagnostic
+}
+
+func (v *validator) report(pointer string, path []string, format string, args ...interface{}) {
+	v.diagnostics = append(v.diagnostics, &Diagnostic{
+		Pointer: pointer,
+		Path:    strings.Join(path, " "),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// validateCommand validates the last command of chain
+func (v *validator) validateCommand(chain []*config.Command, parentPath []string, pointer string) {
+	cmd := chain[len(chain)-1]
+	var path []string
+	path = append(path, parentPath...)
+	if cmd.Name != "" {
+		path = append(path, cmd.Name)
+	}
+
+	if len(chain) > 1 {
+		v.validateCommandName(pointer+"/name", path, cmd.Name, len(chain) == 2)
+		for i, alias := range cmd.Aliases {
+			v.validateCommandName(fmt.Sprintf("%s/aliases/%d", pointer, i), path, alias, len(chain) == 2)
+		}
+	}
+	if cmd.Replacement != "" && v.lookupCommand(cmd.Replacement) == nil {
+		v.report(pointer+"/replacement", path, "replacement %q does not exist", cmd.Replacement)
+	}
+	switch cmd.PersistentPlacement {
+	case "", config.PlacementBefore, config.PlacementAfter:
+	default:
+		v.report(pointer+"/persistentPlacement", path, "persistentPlacement must be %q or %q, got %q", config.PlacementBefore, config.PlacementAfter, cmd.PersistentPlacement)
+	}
+
+	groups := make(map[string]bool, len(cmd.Groups))
+	for i, group := range cmd.Groups {
+		groupPointer := fmt.Sprintf("%s/groups/%d", pointer, i)
+		if group.Name == "" {
+			v.report(groupPointer, path, "group name is empty")
+		} else if groups[group.Name] {
+			v.report(groupPointer, path, "duplicate group %q", group.Name)
+		}
+		groups[group.Name] = true
+	}
+
+	flagOwners := make(map[string]string)
+	for i, opt := range cmd.Options {
+		optPointer := fmt.Sprintf("%s/options/%d", pointer, i)
+		spec := opt.Spec()
+		if spec.Name == "" {
+			v.report(optPointer+"/flags", path, "option flags are empty")
+			continue
+		}
+		optPath := append(path[:len(path):len(path)], opt.Flags)
+		for _, name := range spec.Names() {
+			if owner, ok := flagOwners[name]; ok {
+				v.report(optPointer+"/flags", optPath, "flag %s is already used by %s", name, owner)
+				continue
+			}
+			flagOwners[name] = opt.Flags
+		}
+		v.validateValue(optPointer, optPath, opt.Type, opt.Default, opt.Choices)
+		if opt.Group != "" && !groups[opt.Group] {
+			v.report(optPointer+"/group", optPath, "group %q is not declared in groups", opt.Group)
+		}
+		if opt.Replacement != "" && lookupOption(chain, opt.Replacement) == nil {
+			v.report(optPointer+"/replacement", optPath, "replacement %q does not exist", opt.Replacement)
+		}
+	}
+
+	argNames := make(map[string]bool, len(cmd.Arguments))
+	for i, arg := range cmd.Arguments {
+		argPointer := fmt.Sprintf("%s/arguments/%d", pointer, i)
+		if arg.Name == "" {
+			v.report(argPointer+"/name", path, "argument name is empty")
+			continue
+		}
+		argPath := append(path[:len(path):len(path)], arg.Name)
+		if argNames[arg.Name] {
+			v.report(argPointer+"/name", argPath, "duplicate argument %q", arg.Name)
+		}
+		argNames[arg.Name] = true
+		v.validateValue(argPointer, argPath, arg.Type, arg.Default, arg.Choices)
+	}
+
+	// names and aliases share the URL namespace
+	subNames := make(map[string]bool, len(cmd.Commands))
+	for i, sub := range cmd.Commands {
+		subPointer := fmt.Sprintf("%s/commands/%d", pointer, i)
+		for _, name := range append([]string{sub.Name}, sub.Aliases...) {
+			if name == "" {
+				continue
+			}
+			if subNames[name] {
+				v.report(subPointer, append(path[:len(path):len(path)], sub.Name), "duplicate command name %q", name)
+			}
+			subNames[name] = true
+		}
+		v.validateCommand(append(chain[:len(chain):len(chain)], sub), path, subPointer)
+	}
+}
+
+// validateCommandName checks a subcommand name or alias,
+// which becomes a segment of the command's URL
+func (v *validator) validateCommandName(pointer string, path []string, name string, topLevel bool) {
+	switch {
+	case name == "":
+		v.report(pointer, path, "command name is empty")
+	case !urlSafeNameRegex.MatchString(name):
+		v.report(pointer, path, "command name %q is not URL safe, use letters, digits, '_', '.' and '-'", name)
+	case topLevel && reservedNames[name]:
+		v.report(pointer, path, "command name %q conflicts with the /%s endpoint", name, name)
+	}
+}
+
+// validateValue checks the type and default of an option or argument
+func (v *validator) validateValue(pointer string, path []string, typ string, def string, choices []string) {
+	switch typ {
+	case "", config.TypeString, config.TypeBoolean, config.TypeNumber, config.TypePath:
+	default:
+		v.report(pointer+"/type", path, "type must be %q, %q, %q or %q, got %q", config.TypeString, config.TypeBoolean, config.TypeNumber, config.TypePath, typ)
+		return
+	}
+	if def == "" {
+		return
+	}
+	switch typ {
+	case config.TypeBoolean:
+		if def != "true" && def != "false" {
+			v.report(pointer+"/default", path, "default %q is not a boolean", def)
+		}
+	case config.TypeNumber:
+		if _, err := strconv.ParseFloat(def, 64); err != nil {
+			v.report(pointer+"/default", path, "default %q is not a number", def)
+		}
+	}
+	if len(choices) > 0 && !containsString(choices, def) {
+		v.report(pointer+"/default", path, "default %q is not one of the choices", def)
+	}
+}
+
+// lookupCommand resolves a space separated command path,
+// with or without the root name, commands may be named by
+// an alias
+func (v *validator) lookupCommand(cmdPath string) *config.Command {
+	parts := strings.Fields(cmdPath)
+	if len(parts) > 0 && v.root.Name != "" && parts[0] == v.root.Name {
+		parts = parts[1:]
+	}
+	if len(parts) == 0 {
+		return v.root
+	}
+	cmd := v.root
+	for _, part := range parts {
+		var found *config.Command
+		for _, sub := range cmd.Commands {
+			if sub.Name == part || containsString(sub.Aliases, part) {
+				found = sub
+				break
+			}
+		}
+		if found == nil {
+			return nil
+		}
+		cmd = found
+	}
+	return cmd
+}
+
+// lookupOption finds an option of the last command of chain by
+// any of its flag names, including inherited persistent options
+func lookupOption(chain []*config.Command, flag string) *config.Option {
+	flag = config.ParseFlags(flag).Name
+	options := chain[len(chain)-1].Options
+	for _, opt := range config.InheritedOptions(chain) {
+		options = append(options[:len(options):len(options)], opt.Option)
+	}
+	for _, opt := range options {
+		for _, name := range opt.Spec().Names() {
+			if name == flag {
+				return opt
+			}
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
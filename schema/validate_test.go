@@ -0,0 +1,106 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/xhd2015/cli2web/config"
+)
+
+func TestValidate_Replacements(t *testing.T) {
+	s := &config.Schema{
+		Name: "kool",
+		Commands: []*config.Command{
+			{
+				Name:    "git",
+				Aliases: []string{"g"},
+				Commands: []*config.Command{
+					{Name: "tag-next", Aliases: []string{"tn"}},
+					{
+						Name:      "next-tag",
+						Lifecycle: config.Lifecycle{Deprecated: "renamed", Replacement: "kool git tag-next"},
+					},
+					{
+						Name:      "old-tag",
+						Lifecycle: config.Lifecycle{Deprecated: "removed", Replacement: "git tag-old"},
+						Options: []*config.Option{
+							{Flags: "-f, --format <fmt>"},
+							{Flags: "--json", Lifecycle: config.Lifecycle{Deprecated: "use --format", Replacement: "-f"}},
+							{Flags: "--yaml", Lifecycle: config.Lifecycle{Deprecated: "use --format", Replacement: "--output"}},
+						},
+					},
+					{
+						Name:      "tag",
+						Lifecycle: config.Lifecycle{Deprecated: "renamed", Replacement: "g tn"},
+					},
+				},
+			},
+		},
+	}
+
+	diagnostics := Validate(s)
+	expected := []string{
+		`/commands/0/commands/2/replacement: kool git old-tag: replacement "git tag-old" does not exist`,
+		`/commands/0/commands/2/options/2/replacement: kool git old-tag --yaml: replacement "--output" does not exist`,
+	}
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("Expected %d diagnostics, got %d: %v", len(expected), len(diagnostics), diagnostics)
+	}
+	for i, d := range diagnostics {
+		if d.String() != expected[i] {
+			t.Errorf("Diagnostic %d = %q, expected %q", i, d.String(), expected[i])
+		}
+	}
+}
+
+func TestValidate_Values(t *testing.T) {
+	s := &config.Schema{
+		Name: "kool",
+		Commands: []*config.Command{
+			{
+				Name: "ws",
+			},
+			{
+				Name:    "git",
+				Aliases: []string{"g"},
+				Options: []*config.Option{
+					{Flags: "-f, --format <fmt>", Type: config.TypeString, Default: "xml", Choices: []string{"text", "json"}},
+					{Flags: "--force", Type: config.TypeBoolean, Default: "yes"},
+					{Flags: "-f", Type: "bool"},
+					{Flags: ""},
+				},
+				Arguments: []*config.Argument{
+					{Name: "dir", Type: config.TypeNumber, Default: "1e3"},
+					{Name: ""},
+				},
+				Commands: []*config.Command{
+					{Name: "ws"},
+					{Name: "tag next"},
+				},
+			},
+			{Name: "g"},
+			{Name: ""},
+		},
+	}
+
+	expected := []string{
+		`/commands/0/name: kool ws: command name "ws" conflicts with the /ws endpoint`,
+		`/commands/1/options/0/default: kool git -f, --format <fmt>: default "xml" is not one of the choices`,
+		`/commands/1/options/1/default: kool git --force: default "yes" is not a boolean`,
+		`/commands/1/options/2/flags: kool git -f: flag -f is already used by -f, --format <fmt>`,
+		`/commands/1/options/2/type: kool git -f: type must be "string", "boolean", "number" or "path", got "bool"`,
+		`/commands/1/options/3/flags: kool git: option flags are empty`,
+		`/commands/1/arguments/1/name: kool git: argument name is empty`,
+		`/commands/1/commands/1/name: kool git tag next: command name "tag next" is not URL safe, use letters, digits, '_', '.' and '-'`,
+		`/commands/2: kool g: duplicate command name "g"`,
+		`/commands/3/name: kool: command name is empty`,
+	}
+	diagnostics := Validate(s)
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("Expected %d diagnostics, got %d: %v", len(expected), len(diagnostics), diagnostics)
+	}
+	for i, d := range diagnostics {
+		if d.String() != expected[i] {
+			t.Errorf("Diagnostic %d = %q, expected %q", i, d.String(), expected[i])
+		}
+	}
+}